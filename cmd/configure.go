@@ -45,7 +45,7 @@ func configureConfig() error {
 		}
 	}
 
-	fmt.Print("Enter type[jira]: ")
+	fmt.Print("Enter type[jira|azure|youtrack|linear]: ")
 	endpointType, _ := reader.ReadString('\n')
 	endpointType = endpointType[:len(endpointType)-1]
 	fmt.Print("Enter endpoint: ")
@@ -58,12 +58,15 @@ func configureConfig() error {
 	apiToken, _ := reader.ReadString('\n')
 	apiToken = apiToken[:len(apiToken)-1]
 
-	config := &types.Config{
-		EndpointType: endpointType,
-		Endpoint:     endpoint,
-		Username:     userName,
-		ApiToken:     apiToken,
+	// keep the other sections (backend settings, rules...) of an existing config
+	config := &types.Config{}
+	if configFileExist {
+		configure.ReadConfig(config)
 	}
+	config.EndpointType = endpointType
+	config.Endpoint = endpoint
+	config.Username = userName
+	config.ApiToken = apiToken
 
 	err := configure.WriteConfig(config)
 
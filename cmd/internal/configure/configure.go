@@ -59,6 +59,7 @@ func WriteConfig(config *types.Config) error {
 	defer file.Close()
 
 	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
 	err = encoder.Encode(config)
 
 	return err
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+type meetingRule struct {
+	title     *regexp.Regexp
+	organizer *regexp.Regexp
+	ticket    string
+}
+
+// Meetings logs calendar events to the configured meeting tickets
+type Meetings struct {
+	rules         []meetingRule
+	defaultTicket string
+}
+
+func NewMeetings(config types.CalendarConfig) (*Meetings, error) {
+	m := &Meetings{defaultTicket: config.DefaultTicket}
+	for i, r := range config.Rules {
+		rule := meetingRule{ticket: r.Ticket}
+		var err error
+		if r.Title != "" {
+			if rule.title, err = regexp.Compile("(?i)" + r.Title); err != nil {
+				return nil, fmt.Errorf("invalid title regex in meeting rule %d: %v", i+1, err)
+			}
+		}
+		if r.Organizer != "" {
+			if rule.organizer, err = regexp.Compile("(?i)" + r.Organizer); err != nil {
+				return nil, fmt.Errorf("invalid organizer regex in meeting rule %d: %v", i+1, err)
+			}
+		}
+		m.rules = append(m.rules, rule)
+	}
+	return m, nil
+}
+
+// ticketFor returns the ticket of the first rule matching the event, or the default ticket
+func (m *Meetings) ticketFor(e Event) string {
+	for _, r := range m.rules {
+		if r.title != nil && !r.title.MatchString(e.Summary) {
+			continue
+		}
+		if r.organizer != nil && !r.organizer.MatchString(e.Organizer) {
+			continue
+		}
+		return r.ticket
+	}
+	return m.defaultTicket
+}
+
+// ApplyEvents logs each meeting as a fixed worklog on its meeting ticket, or marks it busy
+// when no ticket matches. Overlapping meetings are only counted once.
+func (m *Meetings) ApplyEvents(events []Event, tickets []types.Ticket, logworkList []types.LogWorkStatus) {
+	busyUntil := time.Time{}
+	for _, e := range events {
+		idx := dayIndex(logworkList, e.Start)
+		if idx < 0 {
+			continue
+		}
+
+		// events are sorted by start, cut the part already covered by the previous meeting
+		start := e.Start
+		if start.Before(busyUntil) {
+			start = busyUntil
+		}
+		if !e.End.After(start) {
+			continue
+		}
+		busyUntil = e.End
+		seconds := int64(e.End.Sub(start).Seconds())
+
+		day := &logworkList[idx]
+		ticket := m.ticketFor(e)
+		if ticket == "" {
+			fmt.Printf("%s %s: %q (%s) has no meeting ticket, only blocking the time\n", start.Format(time.DateOnly), start.Format("15:04"), e.Summary, formatHours(seconds))
+			day.Busy = append(day.Busy, types.Interval{Start: start, End: e.End})
+			continue
+		}
+
+		fmt.Printf("%s %s: %q (%s) -> %s\n", start.Format(time.DateOnly), start.Format("15:04"), e.Summary, formatHours(seconds), ticket)
+		day.AddFixed(types.LogAction{
+			TimeToLog:   seconds,
+			DateToLog:   start,
+			TicketToLog: findTicket(tickets, ticket),
+			Source:      "calendar",
+			Evidence:    []string{e.Summary},
+		})
+	}
+}
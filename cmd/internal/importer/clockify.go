@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/rest"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	clockifyEndpoint = "https://api.clockify.me/api/v1"
+	clockifyPageSize = 1000
+)
+
+type Clockify struct {
+	workspaceID string
+	client      *rest.Client
+}
+
+type clockifyTimeEntry struct {
+	Description string `json:"description"`
+	Tags        []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	TimeInterval struct {
+		Start time.Time  `json:"start"`
+		End   *time.Time `json:"end"`
+	} `json:"timeInterval"`
+}
+
+// NewClockify uses the user's active workspace when workspaceID is empty
+func NewClockify(apiToken string, workspaceID string) *Clockify {
+	return &Clockify{
+		workspaceID: workspaceID,
+		client:      rest.NewClient(clockifyEndpoint, http.Header{"X-Api-Key": []string{apiToken}}),
+	}
+}
+
+func (c *Clockify) GetTimeEntries(from time.Time, to time.Time) ([]types.TimeEntry, error) {
+	var user struct {
+		ID              string `json:"id"`
+		ActiveWorkspace string `json:"activeWorkspace"`
+	}
+	if err := c.client.Do(http.MethodGet, "/user", "", nil, &user); err != nil {
+		return nil, fmt.Errorf("error fetching Clockify user: %v", err)
+	}
+
+	workspaceID := c.workspaceID
+	if workspaceID == "" {
+		workspaceID = user.ActiveWorkspace
+	}
+
+	entries := []types.TimeEntry{}
+	for page := 1; ; page++ {
+		clockifyEntries := []clockifyTimeEntry{}
+		path := fmt.Sprintf("/workspaces/%s/user/%s/time-entries?start=%s&end=%s&hydrated=true&page-size=%d&page=%d",
+			url.PathEscape(workspaceID), url.PathEscape(user.ID),
+			url.QueryEscape(from.UTC().Format(time.RFC3339)), url.QueryEscape(to.UTC().Format(time.RFC3339)),
+			clockifyPageSize, page)
+		if err := c.client.Do(http.MethodGet, path, "", nil, &clockifyEntries); err != nil {
+			return nil, fmt.Errorf("error fetching Clockify time entries: %v", err)
+		}
+
+		for _, e := range clockifyEntries {
+			// running entries have no end yet
+			if e.TimeInterval.End == nil {
+				continue
+			}
+			entry := types.TimeEntry{
+				Description: e.Description,
+				Start:       e.TimeInterval.Start,
+				Duration:    int64(e.TimeInterval.End.Sub(e.TimeInterval.Start).Seconds()),
+			}
+			for _, tag := range e.Tags {
+				entry.Tags = append(entry.Tags, tag.Name)
+			}
+			entries = append(entries, entry)
+		}
+
+		if len(clockifyEntries) < clockifyPageSize {
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,211 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	gitDefaultSessionGap     = 2 * time.Hour
+	gitDefaultSessionPadding = 30 * time.Minute
+	gitFieldSeparator        = "\x1f"
+)
+
+// Commit is a commit of the configured author with the ticket key it was made for
+type Commit struct {
+	Repo    string
+	Time    time.Time
+	Subject string
+	Key     string
+}
+
+// GitActivity estimates the time spent per ticket from local git history
+type GitActivity struct {
+	config         types.GitConfig
+	keyPattern     *regexp.Regexp
+	sessionGap     time.Duration
+	sessionPadding time.Duration
+}
+
+func NewGitActivity(config types.GitConfig) (*GitActivity, error) {
+	g := &GitActivity{
+		config:         config,
+		sessionGap:     gitDefaultSessionGap,
+		sessionPadding: gitDefaultSessionPadding,
+	}
+
+	keyPattern := config.KeyPattern
+	if keyPattern == "" {
+		keyPattern = DefaultKeyPattern
+	}
+	re, err := regexp.Compile(keyPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key pattern %q: %v", keyPattern, err)
+	}
+	g.keyPattern = re
+
+	if config.SessionGap != "" {
+		if g.sessionGap, err = time.ParseDuration(config.SessionGap); err != nil {
+			return nil, fmt.Errorf("invalid git session gap %q: %v", config.SessionGap, err)
+		}
+	}
+	if config.SessionPadding != "" {
+		if g.sessionPadding, err = time.ParseDuration(config.SessionPadding); err != nil {
+			return nil, fmt.Errorf("invalid git session padding %q: %v", config.SessionPadding, err)
+		}
+	}
+
+	switch config.Mode {
+	case "", "weights", "fixed":
+	default:
+		return nil, fmt.Errorf("git mode %q not supported, valid modes are weights/fixed", config.Mode)
+	}
+
+	return g, nil
+}
+
+func git(repo string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s in %s: %v: %s", args[0], repo, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// GetCommits returns the author's commits in [from, to) that mention a ticket key in the
+// commit message or in the branch they were reached from.
+func (g *GitActivity) GetCommits(repo string, from time.Time, to time.Time) ([]Commit, error) {
+	author := g.config.Author
+	if author == "" {
+		email, err := git(repo, "config", "user.email")
+		if err != nil {
+			return nil, err
+		}
+		author = strings.TrimSpace(email)
+	}
+
+	out, err := git(repo, "log", "--all", "--source", "--no-merges",
+		"--author="+author,
+		"--since="+from.Format(time.RFC3339), "--until="+to.Format(time.RFC3339),
+		"--format=%aI%x1f%S%x1f%B%x00")
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []Commit{}
+	for _, record := range strings.Split(out, "\x00") {
+		fields := strings.Split(strings.TrimSpace(record), gitFieldSeparator)
+		if len(fields) < 3 {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			continue
+		}
+		ref, message := fields[1], strings.TrimSpace(fields[2])
+		subject, _, _ := strings.Cut(message, "\n")
+
+		// ưu tiên key trong commit message, sau đó mới đến tên branch
+		key := g.keyPattern.FindString(message)
+		if key == "" {
+			key = g.keyPattern.FindString(ref)
+		}
+		if key == "" {
+			continue
+		}
+
+		commits = append(commits, Commit{Repo: repo, Time: t.In(time.Local), Subject: subject, Key: key})
+	}
+	return commits, nil
+}
+
+// byTime returns a copy of commits in time order, the caller's slice is left as is.
+func byTime(commits []Commit) []Commit {
+	sorted := slices.Clone(commits)
+	slices.SortStableFunc(sorted, func(a, b Commit) int { return a.Time.Compare(b.Time) })
+	return sorted
+}
+
+// Sessions clusters commits (of every repo) into work sessions and returns the seconds
+// spent per ticket per day. Each commit is credited with the time since the previous
+// commit of its session, the first commit of a session gets the session padding.
+func (g *GitActivity) Sessions(commits []Commit) map[string]map[string]int64 {
+	commits = byTime(commits)
+
+	spent := map[string]map[string]int64{}
+	for i, c := range commits {
+		credit := g.sessionPadding
+		if i > 0 {
+			gap := c.Time.Sub(commits[i-1].Time)
+			sameDay := c.Time.Format(time.DateOnly) == commits[i-1].Time.Format(time.DateOnly)
+			if gap <= g.sessionGap && sameDay {
+				credit = gap
+			}
+		}
+
+		day := c.Time.Format(time.DateOnly)
+		if spent[day] == nil {
+			spent[day] = map[string]int64{}
+		}
+		spent[day][c.Key] += int64(credit.Seconds())
+	}
+	return spent
+}
+
+// ApplyCommits feeds the git activity to the allocation, as per-day weights or as fixed
+// worklogs depending on the configured mode.
+func (g *GitActivity) ApplyCommits(commits []Commit, tickets []types.Ticket, logworkList []types.LogWorkStatus) {
+	spent := g.Sessions(commits)
+
+	firstCommit := map[string]time.Time{}
+	subjects := map[string][]string{}
+	// subject theo thứ tự thời gian, không phụ thuộc thứ tự của các repo
+	for _, c := range byTime(commits) {
+		k := c.Time.Format(time.DateOnly) + "/" + c.Key
+		if t, ok := firstCommit[k]; !ok || c.Time.Before(t) {
+			firstCommit[k] = c.Time
+		}
+		subjects[k] = append(subjects[k], c.Subject)
+	}
+
+	for i := range logworkList {
+		day := &logworkList[i]
+		date := day.Date.Format(time.DateOnly)
+
+		keys := make([]string, 0, len(spent[date]))
+		for key := range spent[date] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			seconds := spent[date][key]
+			fmt.Printf("%s: %s ~%s from commits\n", date, key, formatHours(seconds))
+
+			if g.config.Mode == "fixed" {
+				day.AddFixed(types.LogAction{
+					TimeToLog:   seconds,
+					DateToLog:   firstCommit[date+"/"+key].Add(-g.sessionPadding),
+					TicketToLog: findTicket(tickets, key),
+					Source:      "git",
+					Evidence:    subjects[date+"/"+key],
+				})
+			} else {
+				day.AddWeight(key, float64(seconds))
+				day.AddEvidence(key, subjects[date+"/"+key]...)
+			}
+		}
+	}
+}
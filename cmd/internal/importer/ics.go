@@ -0,0 +1,345 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences stops the expansion of recurring events without end
+const maxOccurrences = 5000
+
+// Event is one occurrence of a calendar event
+type Event struct {
+	UID       string
+	Summary   string
+	Organizer string
+	Start     time.Time
+	End       time.Time
+}
+
+type icsEvent struct {
+	Event
+	status       string
+	transparent  bool
+	allDay       bool
+	rrule        map[string]string
+	exdates      []time.Time
+	recurrenceID time.Time
+}
+
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ReadCalendar reads an .ics file and returns the event occurrences overlapping [from, to),
+// recurring events expanded. All-day, cancelled and free (transparent) events are skipped.
+func ReadCalendar(path string, from time.Time, to time.Time) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events, err := parseICS(file)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	// các occurrence bị sửa riêng (RECURRENCE-ID) thay thế occurrence gốc
+	overridden := map[string]bool{}
+	for _, e := range events {
+		if !e.recurrenceID.IsZero() {
+			overridden[e.UID+"/"+e.recurrenceID.UTC().Format(time.RFC3339)] = true
+		}
+	}
+
+	occurrences := []Event{}
+	for _, e := range events {
+		if e.allDay || e.transparent || strings.EqualFold(e.status, "CANCELLED") {
+			continue
+		}
+
+		starts := []time.Time{e.Start}
+		if e.rrule != nil && e.recurrenceID.IsZero() {
+			starts = expandRRule(e, to)
+		}
+
+		duration := e.End.Sub(e.Start)
+		for _, start := range starts {
+			if e.recurrenceID.IsZero() && overridden[e.UID+"/"+start.UTC().Format(time.RFC3339)] {
+				continue
+			}
+			end := start.Add(duration)
+			if !end.After(from) || !start.Before(to) {
+				continue
+			}
+			occurrence := e.Event
+			occurrence.Start, occurrence.End = start, end
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+	return occurrences, nil
+}
+
+// unfoldLines joins the folded content lines of an iCalendar stream (RFC 5545 3.1).
+func unfoldLines(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) icsProperty {
+	p := icsProperty{params: map[string]string{}}
+
+	// dấu ':' đầu tiên nằm ngoài ngoặc kép tách tên/tham số với giá trị
+	inQuotes := false
+	sep := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		p.name = strings.ToUpper(line)
+		return p
+	}
+
+	p.value = line[sep+1:]
+	parts := strings.Split(line[:sep], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p
+}
+
+func parseICS(r io.Reader) ([]icsEvent, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []icsEvent{}
+	var current *icsEvent
+	var duration time.Duration
+	for _, line := range lines {
+		p := parseProperty(line)
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			current = &icsEvent{}
+			duration = 0
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if current == nil {
+				continue
+			}
+			if current.End.IsZero() {
+				current.End = current.Start.Add(duration)
+			}
+			if !current.Start.IsZero() {
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case p.name == "UID":
+			current.UID = p.value
+		case p.name == "SUMMARY":
+			current.Summary = unescapeText(p.value)
+		case p.name == "ORGANIZER":
+			current.Organizer = strings.TrimSpace(p.params["CN"] + " " + strings.TrimPrefix(strings.ToLower(p.value), "mailto:"))
+		case p.name == "STATUS":
+			current.status = p.value
+		case p.name == "TRANSP":
+			current.transparent = strings.EqualFold(p.value, "TRANSPARENT")
+		case p.name == "DTSTART":
+			current.Start, current.allDay, err = parseICSTime(p)
+		case p.name == "DTEND":
+			current.End, _, err = parseICSTime(p)
+		case p.name == "DURATION":
+			duration, err = parseICSDuration(p.value)
+		case p.name == "RECURRENCE-ID":
+			current.recurrenceID, _, err = parseICSTime(p)
+		case p.name == "RRULE":
+			current.rrule = map[string]string{}
+			for _, part := range strings.Split(p.value, ";") {
+				k, v, _ := strings.Cut(part, "=")
+				current.rrule[strings.ToUpper(k)] = strings.ToUpper(v)
+			}
+		case p.name == "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				exdate, _, exErr := parseICSTime(icsProperty{params: p.params, value: v})
+				if exErr == nil {
+					current.exdates = append(current.exdates, exdate)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", p.name, p.value, err)
+		}
+	}
+	return events, nil
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(s)
+}
+
+// parseICSTime parses DATE-TIME values in UTC, floating or TZID form, and DATE values (all-day).
+func parseICSTime(p icsProperty) (time.Time, bool, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", p.value, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t.In(time.Local), false, err
+	}
+
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		// Outlook dùng tên timezone của Windows, không load được thì coi như giờ máy
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	return t.In(time.Local), false, err
+}
+
+// parseICSDuration parses durations like PT1H30M, P1D or P1W.
+func parseICSDuration(s string) (time.Duration, error) {
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+	}
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("duration must start with P")
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	total := time.Duration(0)
+	number := ""
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 'T':
+		case c >= '0' && c <= '9':
+			number += string(c)
+		default:
+			unit, ok := units[c]
+			if !ok || number == "" {
+				return 0, fmt.Errorf("invalid duration unit %q", c)
+			}
+			n, _ := strconv.Atoi(number)
+			total += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	return sign * total, nil
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// expandRRule returns the start of every occurrence before `to`. Only the DAILY and WEEKLY
+// frequencies (with INTERVAL, COUNT, UNTIL, BYDAY) are supported, which covers the usual
+// recurring meetings; other rules only yield the first occurrence.
+func expandRRule(e icsEvent, to time.Time) []time.Time {
+	interval := 1
+	if n, err := strconv.Atoi(e.rrule["INTERVAL"]); err == nil && n > 0 {
+		interval = n
+	}
+	count := -1
+	if n, err := strconv.Atoi(e.rrule["COUNT"]); err == nil {
+		count = n
+	}
+	until := to
+	if v := e.rrule["UNTIL"]; v != "" {
+		if u, _, err := parseICSTime(icsProperty{params: map[string]string{}, value: v}); err == nil && u.Before(until) {
+			until = u.Add(time.Second)
+		}
+	}
+
+	excluded := func(t time.Time) bool {
+		for _, ex := range e.exdates {
+			if ex.Equal(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	byDay := []time.Weekday{}
+	for _, d := range strings.Split(e.rrule["BYDAY"], ",") {
+		if wd, ok := icsWeekdays[d]; ok {
+			byDay = append(byDay, wd)
+		}
+	}
+	if len(byDay) == 0 {
+		byDay = append(byDay, e.Start.Weekday())
+	}
+	sort.Slice(byDay, func(i, j int) bool { return byDay[i] < byDay[j] })
+
+	starts := []time.Time{}
+	emit := func(t time.Time) bool {
+		if !t.Before(until) || count == 0 || len(starts) >= maxOccurrences {
+			return false
+		}
+		if count > 0 {
+			count--
+		}
+		if !excluded(t) {
+			starts = append(starts, t)
+		}
+		return true
+	}
+
+	switch e.rrule["FREQ"] {
+	case "DAILY":
+		for t := e.Start; emit(t); t = t.AddDate(0, 0, interval) {
+		}
+	case "WEEKLY":
+		weekStart := e.Start.AddDate(0, 0, -int(e.Start.Weekday()))
+		for week := weekStart; week.Before(until) && count != 0 && len(starts) < maxOccurrences; week = week.AddDate(0, 0, 7*interval) {
+			for _, wd := range byDay {
+				t := week.AddDate(0, 0, int(wd))
+				if t.Before(e.Start) {
+					continue
+				}
+				if !emit(t) {
+					break
+				}
+			}
+		}
+	default:
+		emit(e.Start)
+	}
+	return starts
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// DefaultKeyPattern matches Jira style ticket keys such as ABC-123
+const DefaultKeyPattern = `\b[A-Z][A-Z0-9]+-\d+\b`
+
+// TimeEntrySource is a time tracking tool the real time entries are pulled from
+type TimeEntrySource interface {
+	// GetTimeEntries returns the finished entries started in [from, to)
+	GetTimeEntries(from time.Time, to time.Time) ([]types.TimeEntry, error)
+}
+
+func NewTimeEntrySource(config types.TimeTrackerConfig) (TimeEntrySource, error) {
+	switch config.Type {
+	case "toggl":
+		return NewToggl(config.ApiToken), nil
+	case "clockify":
+		return NewClockify(config.ApiToken, config.WorkspaceID), nil
+	default:
+		return nil, fmt.Errorf("time tracker type %q not supported, valid types are toggl/clockify", config.Type)
+	}
+}
+
+// DayRange returns the [from, to) range covered by a worklog status list, the days of the run
+func DayRange(logworkList []types.LogWorkStatus) (time.Time, time.Time) {
+	from, to := logworkList[0].Date, logworkList[0].Date
+	for _, day := range logworkList {
+		if day.Date.Before(from) {
+			from = day.Date
+		}
+		if day.Date.After(to) {
+			to = day.Date
+		}
+	}
+	return from, to.AddDate(0, 0, 1)
+}
+
+// dayIndex returns the index of the day containing t, or -1 if t is outside the list
+func dayIndex(logworkList []types.LogWorkStatus, t time.Time) int {
+	y, m, d := t.In(time.Local).Date()
+	for i, day := range logworkList {
+		dy, dm, dd := day.Date.Date()
+		if y == dy && m == dm && d == dd {
+			return i
+		}
+	}
+	return -1
+}
+
+// findTicket returns the ticket with the given key, or a ticket with only the key when
+// it is not in the list (e.g. a ticket that is not assigned to the user anymore)
+func findTicket(tickets []types.Ticket, key string) types.Ticket {
+	for _, t := range tickets {
+		if t.ID == key {
+			return t
+		}
+	}
+	return types.Ticket{ID: key}
+}
+
+// ApplyTimeEntries turns the entries matching a ticket key into fixed worklogs of their
+// day. Unmatched time is left to the allocation algorithm, and days without tracked time
+// get nothing allocated since the tracker is the source of truth. On a day partly logged
+// already, only the tracked time not yet logged on each ticket is added.
+func ApplyTimeEntries(entries []types.TimeEntry, tickets []types.Ticket, logworkList []types.LogWorkStatus, keyPattern string, source string) error {
+	if keyPattern == "" {
+		keyPattern = DefaultKeyPattern
+	}
+	re, err := regexp.Compile(keyPattern)
+	if err != nil {
+		return fmt.Errorf("invalid key pattern %q: %v", keyPattern, err)
+	}
+
+	tracked := make([]int64, len(logworkList))
+	unmatched := make([]int64, len(logworkList))
+	matched := make([][]types.LogAction, len(logworkList))
+
+	for _, e := range entries {
+		idx := dayIndex(logworkList, e.Start)
+		if idx < 0 || e.Duration <= 0 {
+			continue
+		}
+		tracked[idx] += e.Duration
+
+		key := re.FindString(e.Description)
+		for _, tag := range e.Tags {
+			if key != "" {
+				break
+			}
+			key = re.FindString(tag)
+		}
+
+		if key == "" {
+			fmt.Printf(" ⚠️  No ticket key in %q (%s), left to allocation\n", e.Description, e.Start.Format(time.DateTime))
+			unmatched[idx] += e.Duration
+			continue
+		}
+
+		matched[idx] = append(matched[idx], types.LogAction{
+			TimeToLog:   e.Duration,
+			DateToLog:   e.Start.In(time.Local),
+			TicketToLog: findTicket(tickets, key),
+			Source:      source,
+			Evidence:    []string{e.Description},
+		})
+	}
+
+	for i := range logworkList {
+		day := &logworkList[i]
+		allocatable := unmatched[i]
+
+		if tracked[i] > 0 && day.TimeSpent >= tracked[i] {
+			fmt.Printf(" ⏭️ %s already has %s logged for %s tracked, skipping\n", day.Date.Format(time.DateOnly), formatHours(day.TimeSpent), formatHours(tracked[i]))
+			allocatable = 0
+		} else {
+			// worklog đã có của từng ticket được trừ vào các entry của ticket đó theo thứ tự
+			logged := map[string]int64{}
+			for id, spent := range day.Tickets {
+				logged[id] = spent
+			}
+			covered := int64(0)
+			for _, action := range matched[i] {
+				id := action.TicketToLog.ID
+				used := min(logged[id], action.TimeToLog)
+				logged[id] -= used
+				covered += used
+				if action.TimeToLog -= used; action.TimeToLog > 0 {
+					day.AddFixed(action)
+				}
+			}
+			if covered > 0 {
+				fmt.Printf(" ⏭️ %s already has %s of the tracked tickets logged, adding the rest\n", day.Date.Format(time.DateOnly), formatHours(covered))
+			}
+			// worklog không khớp entry nào coi như đã log phần thời gian không có ticket
+			allocatable = max(unmatched[i]-(day.TimeSpent-covered), 0)
+		}
+
+		day.Allocatable = &allocatable
+	}
+
+	return nil
+}
+
+func formatHours(seconds int64) string {
+	return fmt.Sprintf("%.2fh", float64(seconds)/3600)
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestApplyTimeEntriesAddsOnlyTheDifference(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	entries := []types.TimeEntry{
+		{Description: "APP-1 login", Start: monday.Add(8 * time.Hour), Duration: 2 * 3600},
+		{Description: "APP-2 review", Start: monday.Add(10 * time.Hour), Duration: 3600},
+		{Description: "email", Start: monday.Add(11 * time.Hour), Duration: 30 * 60},
+	}
+	// APP-1 đã được log 1h trước đó
+	days := []types.LogWorkStatus{{Date: monday}}
+	days[0].AddWorklog(monday.Add(8*time.Hour), 3600)
+	days[0].AddTicketTime("APP-1", 3600)
+
+	if err := ApplyTimeEntries(entries, nil, days, "", "toggl"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int64{}
+	for _, f := range days[0].Fixed {
+		got[f.TicketToLog.ID] += f.TimeToLog
+	}
+	if got["APP-1"] != 3600 || got["APP-2"] != 3600 {
+		t.Errorf("fixed = %v, want 1h on APP-1 and 1h on APP-2", got)
+	}
+	if *days[0].Allocatable != 30*60 {
+		t.Errorf("allocatable = %d, want the 30m without ticket", *days[0].Allocatable)
+	}
+}
+
+func TestApplyTimeEntriesSkipsDaysFullyLogged(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	entries := []types.TimeEntry{{Description: "APP-1", Start: monday.Add(8 * time.Hour), Duration: 3600}}
+	days := []types.LogWorkStatus{{Date: monday}}
+	days[0].Add(2 * 3600)
+
+	if err := ApplyTimeEntries(entries, nil, days, "", "toggl"); err != nil {
+		t.Fatal(err)
+	}
+	if len(days[0].Fixed) != 0 || *days[0].Allocatable != 0 {
+		t.Errorf("fixed = %+v, allocatable = %d, want nothing", days[0].Fixed, *days[0].Allocatable)
+	}
+}
+
+func TestGitSessionsLeavesCommitsInPlace(t *testing.T) {
+	g, err := NewGitActivity(types.GitConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	// commit của hai repo, repo sau có commit sớm hơn
+	commits := []Commit{
+		{Repo: "api", Time: monday.Add(10 * time.Hour), Subject: "APP-1 second", Key: "APP-1"},
+		{Repo: "web", Time: monday.Add(9 * time.Hour), Subject: "APP-1 first", Key: "APP-1"},
+	}
+	spent := g.Sessions(commits)
+
+	if commits[0].Repo != "api" || commits[1].Repo != "web" {
+		t.Errorf("commits = %+v, want the caller's order kept", commits)
+	}
+	if spent["2024-05-06"]["APP-1"] != int64((gitDefaultSessionPadding + time.Hour).Seconds()) {
+		t.Errorf("spent = %v, want the padding and the 1h between the commits", spent)
+	}
+
+	days := []types.LogWorkStatus{{Date: monday}}
+	g.ApplyCommits(commits, nil, days)
+	if evidence := days[0].Evidence["APP-1"]; len(evidence) != 2 || evidence[0] != "APP-1 first" {
+		t.Errorf("evidence = %v, want the subjects in time order", evidence)
+	}
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/constant"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// defaultRecurringAt is used when a rule has no time, the start of the shift
+const defaultRecurringAt = "07:30"
+
+type recurringRule struct {
+	types.RecurringRule
+	duration time.Duration
+	at       time.Duration
+	weekdays []time.Weekday
+}
+
+// RecurringWorklogs are the admin worklogs (standup, retro...) logged every matching day
+type RecurringWorklogs struct {
+	rules []recurringRule
+}
+
+func NewRecurringWorklogs(rules []types.RecurringRule) (*RecurringWorklogs, error) {
+	r := &RecurringWorklogs{}
+	for _, rule := range rules {
+		parsed, err := parseRecurringRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurring rule on %s: %v", rule.Ticket, err)
+		}
+		r.rules = append(r.rules, parsed)
+	}
+	return r, nil
+}
+
+func parseRecurringRule(rule types.RecurringRule) (recurringRule, error) {
+	parsed := recurringRule{RecurringRule: rule}
+
+	if rule.Ticket == "" {
+		return parsed, fmt.Errorf("ticket is required")
+	}
+
+	var err error
+	if parsed.duration, err = time.ParseDuration(rule.Duration); err != nil || parsed.duration <= 0 {
+		return parsed, fmt.Errorf("invalid duration %q", rule.Duration)
+	}
+
+	at := rule.At
+	if at == "" {
+		at = defaultRecurringAt
+	}
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		return parsed, fmt.Errorf("invalid time %q, expected HH:MM", rule.At)
+	}
+	parsed.at = time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute
+
+	for _, day := range strings.Split(strings.ToLower(rule.Every), ",") {
+		day = strings.TrimSpace(day)
+		if day == "daily" {
+			parsed.weekdays = append(parsed.weekdays, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+			continue
+		}
+		found := false
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			name := strings.ToLower(wd.String())
+			if day == name || day == name[:3] {
+				parsed.weekdays = append(parsed.weekdays, wd)
+				found = true
+			}
+		}
+		if !found {
+			return parsed, fmt.Errorf("invalid day %q, expected daily or weekday names", day)
+		}
+	}
+
+	return parsed, nil
+}
+
+// Apply adds the recurring worklogs as fixed worklogs of each matching day. Days that
+// already have a worklog on the rule's ticket, in the backend (e.g. a standup entered by
+// hand) or fixed (e.g. the same meeting imported from the calendar), are skipped. A rule is
+// cut to what is left of the shift, and skipped on days already fully logged.
+func (r *RecurringWorklogs) Apply(tickets []types.Ticket, logworkList []types.LogWorkStatus) {
+	for i := range logworkList {
+		day := &logworkList[i]
+
+		for _, rule := range r.rules {
+			if !slices.Contains(rule.weekdays, day.Date.Weekday()) {
+				continue
+			}
+			if day.Tickets[rule.Ticket] > 0 {
+				fmt.Printf("%s: %s already logged, skipping\n", day.Date.Format(time.DateOnly), rule.Ticket)
+				continue
+			}
+			if slices.ContainsFunc(day.Fixed, func(f types.LogAction) bool { return f.TicketToLog.ID == rule.Ticket }) {
+				continue
+			}
+			free := constant.DefaultShiftSeconds - day.TimeSpent - day.FixedTime()
+			if free <= 0 {
+				continue
+			}
+			duration := min(int64(rule.duration.Seconds()), free)
+
+			ticket := findTicket(tickets, rule.Ticket)
+			if ticket.Summary == "" {
+				ticket.Summary = rule.Summary
+			}
+
+			fmt.Printf("%s: %s %s (%s)\n", day.Date.Format(time.DateOnly), rule.Ticket, formatHours(duration), ticket.Summary)
+			day.AddFixed(types.LogAction{
+				TimeToLog:   duration,
+				DateToLog:   day.Date.Add(rule.at),
+				TicketToLog: ticket,
+				Source:      "recurring",
+				Evidence:    []string{rule.Summary},
+			})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/constant"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestRecurringSkipsTicketsAlreadyLogged(t *testing.T) {
+	recurring, err := NewRecurringWorklogs([]types.RecurringRule{{Ticket: "APP-1", Every: "daily", Duration: "15m", At: "09:00"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	days := []types.LogWorkStatus{{Date: monday}, {Date: monday.AddDate(0, 0, 1)}}
+	// thứ hai đã có standup 15m nhập tay trên Jira
+	days[0].AddWorklog(monday.Add(9*time.Hour), 15*60)
+	days[0].AddTicketTime("APP-1", 15*60)
+
+	recurring.Apply(nil, days)
+
+	if len(days[0].Fixed) != 0 {
+		t.Errorf("Monday: %d recurring worklogs added over the existing one", len(days[0].Fixed))
+	}
+	if len(days[1].Fixed) != 1 || days[1].Fixed[0].TimeToLog != 15*60 {
+		t.Errorf("Tuesday: fixed = %+v, want one 15m worklog", days[1].Fixed)
+	}
+}
+
+func TestRecurringFitsTheRemainingShift(t *testing.T) {
+	recurring, err := NewRecurringWorklogs([]types.RecurringRule{{Ticket: "APP-1", Every: "daily", Duration: "1h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	days := []types.LogWorkStatus{{Date: monday}, {Date: monday.AddDate(0, 0, 1)}}
+	days[0].Add(constant.DefaultShiftSeconds - 20*60)
+	days[1].Add(constant.DefaultShiftSeconds)
+
+	recurring.Apply(nil, days)
+
+	if len(days[0].Fixed) != 1 || days[0].Fixed[0].TimeToLog != 20*60 {
+		t.Errorf("Monday: fixed = %+v, want one worklog of the 20m left", days[0].Fixed)
+	}
+	if len(days[1].Fixed) != 0 {
+		t.Errorf("Tuesday: %d recurring worklogs added on a full day", len(days[1].Fixed))
+	}
+}
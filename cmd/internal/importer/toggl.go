@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/rest"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const togglEndpoint = "https://api.track.toggl.com/api/v9"
+
+type Toggl struct {
+	client *rest.Client
+}
+
+type togglTimeEntry struct {
+	Description string    `json:"description"`
+	Start       time.Time `json:"start"`
+	Duration    int64     `json:"duration"`
+	Tags        []string  `json:"tags"`
+}
+
+func NewToggl(apiToken string) *Toggl {
+	return &Toggl{
+		client: rest.NewClient(togglEndpoint, rest.BasicAuthHeader(apiToken, "api_token")),
+	}
+}
+
+func (t *Toggl) GetTimeEntries(from time.Time, to time.Time) ([]types.TimeEntry, error) {
+	togglEntries := []togglTimeEntry{}
+	path := fmt.Sprintf("/me/time_entries?start_date=%s&end_date=%s", url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(to.Format(time.RFC3339)))
+	if err := t.client.Do(http.MethodGet, path, "", nil, &togglEntries); err != nil {
+		return nil, fmt.Errorf("error fetching Toggl time entries: %v", err)
+	}
+
+	entries := []types.TimeEntry{}
+	for _, e := range togglEntries {
+		// running entries have a negative duration
+		if e.Duration <= 0 {
+			continue
+		}
+		entries = append(entries, types.TimeEntry{
+			Description: e.Description,
+			Tags:        e.Tags,
+			Start:       e.Start,
+			Duration:    e.Duration,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,114 @@
+package logwork
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+)
+
+// How the remaining estimate moves when a worklog is written, as Jira's adjustEstimate.
+const (
+	adjustAuto   = "auto"
+	adjustLeave  = "leave"
+	adjustNew    = "new"
+	adjustManual = "manual"
+)
+
+// adjustEstimate is one parsed adjustment: "auto", "leave", "new:2h" or "manual:30m".
+type adjustEstimate struct {
+	mode  string
+	value int64
+}
+
+func parseAdjustEstimate(text string) (adjustEstimate, error) {
+	mode, value, _ := strings.Cut(strings.TrimSpace(text), ":")
+	a := adjustEstimate{mode: strings.ToLower(strings.TrimSpace(mode))}
+
+	switch a.mode {
+	case "":
+		a.mode = adjustAuto
+	case adjustAuto, adjustLeave:
+	case adjustNew, adjustManual:
+		// cho phép viết kiểu Jira "1h 30m"
+		d, err := time.ParseDuration(strings.ReplaceAll(value, " ", ""))
+		if err != nil || d < 0 {
+			return a, fmt.Errorf("invalid %s estimate %q, expected e.g. %s:2h", a.mode, value, a.mode)
+		}
+		a.value = int64(d.Seconds())
+	default:
+		return a, fmt.Errorf("invalid estimate adjustment %q, expected auto, leave, new:<duration> or manual:<duration>", text)
+	}
+
+	return a, nil
+}
+
+// apply returns the remaining estimate after logging timeSpent.
+func (a adjustEstimate) apply(remaining int64, timeSpent int64) int64 {
+	switch a.mode {
+	case adjustLeave:
+		return remaining
+	case adjustNew:
+		return a.value
+	case adjustManual:
+		return max(remaining-a.value, 0)
+	default:
+		return max(remaining-timeSpent, 0)
+	}
+}
+
+func (a adjustEstimate) String() string {
+	if a.mode == adjustNew || a.mode == adjustManual {
+		return a.mode + ":" + helper.FormatEstimate(a.value)
+	}
+	return a.mode
+}
+
+// adjustRules maps a ticket key, project key or "*" to its adjustment.
+type adjustRules map[string]adjustEstimate
+
+func newAdjustRules(config map[string]string) (adjustRules, error) {
+	rules := adjustRules{}
+	for key, text := range config {
+		a, err := parseAdjustEstimate(text)
+		if err != nil {
+			return nil, fmt.Errorf("adjust estimate of %s: %v", key, err)
+		}
+		rules[key] = a
+	}
+	return rules, nil
+}
+
+// forTicket returns the adjustment of a ticket, auto when nothing is configured.
+func (r adjustRules) forTicket(ticketID string) adjustEstimate {
+	if a, ok := ticketLookup(r, ticketID); ok {
+		return a
+	}
+	return adjustEstimate{mode: adjustAuto}
+}
+
+// adjustRun hands out the adjustment of each worklog of one run. new and manual are meant for
+// the ticket, not for each worklog: they go with its first worklog and the next ones leave the
+// remaining estimate alone. auto follows every worklog.
+type adjustRun struct {
+	rules   adjustRules
+	applied map[string]bool
+}
+
+func (r adjustRules) newRun() *adjustRun {
+	return &adjustRun{rules: r, applied: map[string]bool{}}
+}
+
+// next returns the adjustment of the next worklog of a ticket.
+func (r *adjustRun) next(ticketID string) adjustEstimate {
+	a := r.rules.forTicket(ticketID)
+	if a.mode != adjustNew && a.mode != adjustManual {
+		return a
+	}
+	if r.applied[ticketID] {
+		return adjustEstimate{mode: adjustLeave}
+	}
+	r.applied[ticketID] = true
+	return a
+}
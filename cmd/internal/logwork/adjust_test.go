@@ -0,0 +1,81 @@
+package logwork
+
+import (
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestAdjustRunAppliesNewAndManualOncePerTicket(t *testing.T) {
+	rules, err := newAdjustRules(map[string]string{"*": "manual:30m", "APP-2": "new:2h", "APP-3": "auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// APP-1 được log 3 ngày, mỗi ngày tách 2 worklog quanh giờ nghỉ trưa
+	remaining := map[string]int64{"APP-1": 10 * 3600, "APP-2": 10 * 3600, "APP-3": 10 * 3600}
+	run := rules.newRun()
+	for _, id := range []string{"APP-1", "APP-1", "APP-2", "APP-1", "APP-1", "APP-2", "APP-3", "APP-1", "APP-1", "APP-3"} {
+		remaining[id] = run.next(id).apply(remaining[id], 3600)
+	}
+
+	want := map[string]int64{"APP-1": 9*3600 + 30*60, "APP-2": 2 * 3600, "APP-3": 8 * 3600}
+	for id, w := range want {
+		if remaining[id] != w {
+			t.Errorf("%s: remaining = %d, want %d", id, remaining[id], w)
+		}
+	}
+}
+
+func TestAdjustRunLeavesLaterWorklogs(t *testing.T) {
+	rules, err := newAdjustRules(map[string]string{"*": "new:1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := rules.newRun()
+	if got := run.next("APP-1"); got.mode != adjustNew {
+		t.Errorf("first worklog: %s, want new:1h", got)
+	}
+	if got := run.next("APP-1"); got.mode != adjustLeave {
+		t.Errorf("second worklog: %s, want leave", got)
+	}
+
+	// jiraAdjustOptions của worklog sau không được gửi newEstimate nữa
+	options := jiraAdjustOptions(run.next("APP-1")).(struct {
+		AdjustEstimate string `url:"adjustEstimate"`
+		NewEstimate    string `url:"newEstimate,omitempty"`
+		ReduceBy       string `url:"reduceBy,omitempty"`
+	})
+	if options.AdjustEstimate != adjustLeave || options.NewEstimate != "" {
+		t.Errorf("options = %+v, want leave", options)
+	}
+}
+
+func TestAdjustRemainingEstimatesOfPlan(t *testing.T) {
+	rules, err := newAdjustRules(map[string]string{"*": "manual:30m", "APP-2": "auto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual := types.Ticket{ID: "APP-1", Est: 20 * 3600, Remaining: 10 * 3600}
+	auto := types.Ticket{ID: "APP-2", Est: 20 * 3600, Remaining: 10 * 3600}
+	actions := []types.LogAction{}
+	for day := 0; day < 3; day++ {
+		date := time.Date(2026, 10, 19+day, 9, 0, 0, 0, time.Local)
+		actions = append(actions,
+			types.LogAction{TicketToLog: manual, DateToLog: date, TimeToLog: 3 * 3600},
+			types.LogAction{TicketToLog: manual, DateToLog: date.Add(4 * time.Hour), TimeToLog: 3600},
+			types.LogAction{TicketToLog: auto, DateToLog: date.Add(5 * time.Hour), TimeToLog: 3600})
+	}
+
+	order, before, after := remainingEstimates(actions, rules)
+	if len(order) != 2 || order[0] != "APP-1" || order[1] != "APP-2" {
+		t.Fatalf("order = %v", order)
+	}
+	if before["APP-1"] != 10*3600 || after["APP-1"] != 9*3600+30*60 {
+		t.Errorf("APP-1: %d -> %d, want 10h -> 9h30m", before["APP-1"], after["APP-1"])
+	}
+	if after["APP-2"] != 7*3600 {
+		t.Errorf("APP-2: -> %d, want 7h", after["APP-2"])
+	}
+}
@@ -4,35 +4,74 @@ import (
 	"slices"
 	"time"
 
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/constant"
 	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
 )
 
-func defaultLogWorkAlgorithm(ticket []types.Ticket, logworkList []types.LogWorkStatus) ([]types.LogAction, error) {
-	const defaultShiftTime = 7.5 // giờ
-	const shiftSeconds = int64(defaultShiftTime * 3600)
+// startShiftHour is when the shift starts (7h30 sáng)
+const startShiftHour = 7*time.Hour + 30*time.Minute
+
+func defaultLogWorkAlgorithm(ticket []types.Ticket, logworkList []types.LogWorkStatus, config types.AllocationConfig) ([]types.LogAction, error) {
+	const shiftSeconds = constant.DefaultShiftSeconds
 	workingDay := []int{1, 2, 3, 4, 5}
-	startShiftHour := 7*time.Hour + 30*time.Minute // 7h30 sáng
+
+	round, err := newRounding(config)
+	if err != nil {
+		return nil, err
+	}
+	dayShift, err := newShift(config)
+	if err != nil {
+		return nil, err
+	}
+	overflowPolicy, err := newOverflow(config)
+	if err != nil {
+		return nil, err
+	}
 
 	logActionList := []types.LogAction{}
 
 	for i := range logworkList {
 		day := logworkList[i]
+		// thời gian đã chia cho từng ticket trong ngày, dùng cho max-per-day
+		dayLogged := map[string]int64{}
+
+		// worklog cố định (import từ Toggl/Clockify, lịch họp, pin...) luôn được log, kể cả ngày nghỉ
+		for _, f := range day.Fixed {
+			logActionList = append(logActionList, f)
+			markLogged(ticket, f)
+			dayLogged[f.TicketToLog.ID] += f.TimeToLog
+		}
+
 		if !slices.Contains(workingDay, int(day.Date.Weekday())) {
 			continue
 		}
 
 		// còn lại trong ca hôm đó
-		remainingShift := shiftSeconds - day.TimeSpent
+		remainingShift := shiftSeconds - day.TimeSpent - day.FixedTime() - day.BusyTime()
+		if day.Allocatable != nil && *day.Allocatable < remainingShift {
+			remainingShift = *day.Allocatable
+		}
 		if remainingShift <= 0 {
 			continue
 		}
 
+		dayActions := []types.LogAction{}
+
+		// chia theo trọng số (git, hint weight) trước, phần dư dùng thuật toán tham lam
+		if weights := dayWeights(ticket, day); len(weights) > 0 {
+			weighted := allocateWeighted(ticket, weights, remainingShift, day.Date.Add(startShiftHour), dayLogged)
+			for _, action := range weighted {
+				remainingShift -= action.TimeToLog
+			}
+			dayActions = append(dayActions, weighted...)
+		}
+
 		// log đến khi hết ca hoặc hết ticket khả thi
 		for remainingShift > 0 && len(ticket) > 0 {
 			assigned := false
 			for tIdx := range ticket {
 				t := &ticket[tIdx]
-				remainingEst := t.Est - t.EstimatedLogged
+				remainingEst := capacity(t, dayLogged)
 				if remainingEst <= 0 {
 					continue
 				}
@@ -48,7 +87,7 @@ func defaultLogWorkAlgorithm(ticket []types.Ticket, logworkList []types.LogWorkS
 				}
 
 				// thêm log action
-				logActionList = append(logActionList, types.LogAction{
+				dayActions = append(dayActions, types.LogAction{
 					TimeToLog:   timeToLog,
 					TicketToLog: *t,
 					DateToLog:   day.Date.Add(startShiftHour),
@@ -56,6 +95,7 @@ func defaultLogWorkAlgorithm(ticket []types.Ticket, logworkList []types.LogWorkS
 
 				// cập nhật lại estimate và shift còn lại
 				t.EstimatedLogged += timeToLog
+				dayLogged[t.ID] += timeToLog
 				remainingShift -= timeToLog
 				assigned = true
 
@@ -70,7 +110,117 @@ func defaultLogWorkAlgorithm(ticket []types.Ticket, logworkList []types.LogWorkS
 				break
 			}
 		}
+
+		// mọi ticket đã hết estimate mà ca vẫn còn -> xử lý theo policy
+		if remainingShift > 0 {
+			dayActions = append(dayActions, overflowPolicy.fill(ticket, day, remainingShift, dayLogged)...)
+		}
+
+		for _, action := range dayShift.schedule(day, round.apply(ticket, dayActions, dayLogged), round.unit) {
+			action.Evidence = day.Evidence[action.TicketToLog.ID]
+			logActionList = append(logActionList, action)
+		}
 	}
 
 	return logActionList, nil
 }
+
+// markLogged counts a fixed worklog against its ticket so the algorithm does not
+// allocate the same estimate twice.
+func markLogged(ticket []types.Ticket, action types.LogAction) {
+	for i := range ticket {
+		if ticket[i].ID == action.TicketToLog.ID {
+			ticket[i].EstimatedLogged += action.TimeToLog
+			return
+		}
+	}
+}
+
+// capacity returns how much more time can be allocated to t today: the remaining
+// estimate, limited by the max-per-day hint, and nothing for excluded tickets.
+func capacity(t *types.Ticket, dayLogged map[string]int64) int64 {
+	if t.Hints.Exclude {
+		return 0
+	}
+	c := t.Est - t.EstimatedLogged
+	if t.Hints.MaxPerDay > 0 {
+		c = min(c, t.Hints.MaxPerDay-dayLogged[t.ID])
+	}
+	return c
+}
+
+// dayWeights returns the allocation weight of each ticket for the day. Git activity gives
+// per-day weights, scaled by the ticket weight hint. Without git activity, weights are only
+// used when a ticket has a weight hint, the other tickets then weigh 1.
+func dayWeights(ticket []types.Ticket, day types.LogWorkStatus) map[string]float64 {
+	hinted := slices.ContainsFunc(ticket, func(t types.Ticket) bool { return t.Hints.Weight > 0 })
+	if len(day.Weights) == 0 && !hinted {
+		return nil
+	}
+
+	weights := map[string]float64{}
+	for _, t := range ticket {
+		w := t.Hints.Weight
+		if w <= 0 {
+			w = 1
+		}
+		if len(day.Weights) > 0 {
+			w *= day.Weights[t.ID]
+		}
+		if w > 0 {
+			weights[t.ID] = w
+		}
+	}
+	return weights
+}
+
+// allocateWeighted spreads shift over the tickets having a weight, proportionally to the
+// weight and capped by each ticket's capacity for the day.
+func allocateWeighted(ticket []types.Ticket, weights map[string]float64, shift int64, date time.Time, dayLogged map[string]int64) []types.LogAction {
+	allocated := make([]int64, len(ticket))
+	remaining := shift
+
+	for remaining > 0 {
+		totalWeight := 0.0
+		for i := range ticket {
+			if weights[ticket[i].ID] > 0 && capacity(&ticket[i], dayLogged)-allocated[i] > 0 {
+				totalWeight += weights[ticket[i].ID]
+			}
+		}
+		if totalWeight == 0 {
+			break
+		}
+
+		given := int64(0)
+		round := remaining
+		for i := range ticket {
+			w := weights[ticket[i].ID]
+			free := capacity(&ticket[i], dayLogged) - allocated[i]
+			if w <= 0 || free <= 0 {
+				continue
+			}
+			share := min(int64(float64(round)*w/totalWeight), free, remaining)
+			allocated[i] += share
+			remaining -= share
+			given += share
+		}
+		if given == 0 {
+			break
+		}
+	}
+
+	logActionList := []types.LogAction{}
+	for i := range ticket {
+		if allocated[i] <= 0 {
+			continue
+		}
+		logActionList = append(logActionList, types.LogAction{
+			TimeToLog:   allocated[i],
+			TicketToLog: ticket[i],
+			DateToLog:   date,
+		})
+		ticket[i].EstimatedLogged += allocated[i]
+		dayLogged[ticket[i].ID] += allocated[i]
+	}
+	return logActionList
+}
@@ -0,0 +1,418 @@
+package logwork
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/rest"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	azureBackend       = "azure"
+	azureAPIVersion    = "7.0"
+	azureBatchSize     = 200
+	azureOriginalField = "Microsoft.VSTS.Scheduling.OriginalEstimate"
+	azureCompleteField = "Microsoft.VSTS.Scheduling.CompletedWork"
+	azureRemainField   = "Microsoft.VSTS.Scheduling.RemainingWork"
+	azureHistoryField  = "System.History"
+)
+
+// AzureDevOps logs work on Azure Boards tasks. Boards has no worklog entity, so hours are
+// added to CompletedWork and every worklog is also kept in the local ledger.
+type AzureDevOps struct {
+	endpoint   string
+	userName   string
+	apiToken   string
+	dryRun     bool
+	allocation types.AllocationConfig
+	worklog    types.WorklogConfig
+	client     *rest.Client
+	estimation types.EstimationConfig
+	// yes accepts the estimate suggestions without review, see reviewSuggestions
+	yes bool
+	// days are the days of the run, --from / --to or the current week
+	days period
+}
+
+type azureWorkItem struct {
+	ID     int `json:"id"`
+	Fields struct {
+		Title            string    `json:"System.Title"`
+		State            string    `json:"System.State"`
+		WorkItemType     string    `json:"System.WorkItemType"`
+		TeamProject      string    `json:"System.TeamProject"`
+		Tags             string    `json:"System.Tags"`
+		Parent           int       `json:"System.Parent"`
+		CreatedDate      time.Time `json:"System.CreatedDate"`
+		ChangedDate      time.Time `json:"System.ChangedDate"`
+		OriginalEstimate float64   `json:"Microsoft.VSTS.Scheduling.OriginalEstimate"`
+		CompletedWork    float64   `json:"Microsoft.VSTS.Scheduling.CompletedWork"`
+		RemainingWork    float64   `json:"Microsoft.VSTS.Scheduling.RemainingWork"`
+	} `json:"fields"`
+}
+
+type azurePatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// NewAzureDevOps expects the endpoint in the form https://dev.azure.com/{organization}/{project}
+// and a personal access token with Work Items read & write scope.
+func NewAzureDevOps(config *types.Config) *AzureDevOps {
+	return &AzureDevOps{
+		endpoint:   config.Endpoint,
+		userName:   config.Username,
+		apiToken:   config.ApiToken,
+		dryRun:     config.DryRun,
+		allocation: config.Allocation,
+		worklog:    config.Worklog,
+		estimation: config.Estimation,
+		yes:        config.Yes,
+		days:       newPeriod(config),
+		client:     rest.NewClient(config.Endpoint, rest.BasicAuthHeader(config.Username, config.ApiToken)),
+	}
+}
+
+// queryWorkItems runs a WIQL query and loads the matching work items in batches.
+func (a *AzureDevOps) queryWorkItems(wiql string, top int) ([]azureWorkItem, error) {
+	var result struct {
+		WorkItems []struct {
+			ID int `json:"id"`
+		} `json:"workItems"`
+	}
+
+	path := fmt.Sprintf("/_apis/wit/wiql?api-version=%s&$top=%d", azureAPIVersion, top)
+	if err := a.client.Do(http.MethodPost, path, "", map[string]string{"query": wiql}, &result); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(result.WorkItems))
+	for _, w := range result.WorkItems {
+		ids = append(ids, strconv.Itoa(w.ID))
+	}
+
+	workItems := []azureWorkItem{}
+	for start := 0; start < len(ids); start += azureBatchSize {
+		end := min(start+azureBatchSize, len(ids))
+
+		var batch struct {
+			Value []azureWorkItem `json:"value"`
+		}
+		path := fmt.Sprintf("/_apis/wit/workitems?ids=%s&api-version=%s", strings.Join(ids[start:end], ","), azureAPIVersion)
+		if err := a.client.Do(http.MethodGet, path, "", nil, &batch); err != nil {
+			return nil, err
+		}
+		workItems = append(workItems, batch.Value...)
+	}
+
+	return workItems, nil
+}
+
+func (a *AzureDevOps) getWorkItem(id string) (*azureWorkItem, error) {
+	workItem := &azureWorkItem{}
+	path := fmt.Sprintf("/_apis/wit/workitems/%s?api-version=%s", url.PathEscape(id), azureAPIVersion)
+	if err := a.client.Do(http.MethodGet, path, "", nil, workItem); err != nil {
+		return nil, err
+	}
+	return workItem, nil
+}
+
+func (a *AzureDevOps) updateWorkItem(id string, operations []azurePatchOperation) error {
+	path := fmt.Sprintf("/_apis/wit/workitems/%s?api-version=%s", url.PathEscape(id), azureAPIVersion)
+	return a.client.Do(http.MethodPatch, path, "application/json-patch+json", operations, nil)
+}
+
+func (w *azureWorkItem) toTicket() types.Ticket {
+	ticket := types.Ticket{
+		ID:              strconv.Itoa(w.ID),
+		Summary:         w.Fields.Title,
+		Est:             hoursToSeconds(w.Fields.OriginalEstimate),
+		EstimatedLogged: hoursToSeconds(w.Fields.CompletedWork),
+		Remaining:       hoursToSeconds(w.Fields.RemainingWork),
+		Status:          w.Fields.State,
+		Type:            w.Fields.WorkItemType,
+		Project:         w.Fields.TeamProject,
+		Created:         jira.Time(w.Fields.CreatedDate),
+		Updated:         jira.Time(w.Fields.ChangedDate),
+	}
+	if w.Fields.Parent != 0 {
+		ticket.Parent = strconv.Itoa(w.Fields.Parent)
+	}
+	for _, tag := range strings.Split(w.Fields.Tags, ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			ticket.Labels = append(ticket.Labels, tag)
+		}
+	}
+	return ticket
+}
+
+func hoursToSeconds(hours float64) int64 {
+	return int64(hours * 3600)
+}
+
+func secondsToHours(seconds int64) float64 {
+	return float64(seconds) / 3600
+}
+
+func (a *AzureDevOps) GetTicketToLog() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket able to log-------------------")
+	wiql := `SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project AND [System.WorkItemType] = 'Task' AND [System.AssignedTo] = @Me AND [System.State] NOT IN ('Closed', 'Done', 'Removed') ORDER BY [System.CreatedDate] DESC`
+
+	workItems, err := a.queryWorkItems(wiql, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Azure DevOps work items: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
+	for i := range workItems {
+		ticket := workItems[i].toTicket()
+		fmt.Printf("Issue: %s, Summary %s, Est: %s, Status: %s\n", ticket.ID, ticket.Summary, helper.FormatEstimate(ticket.Est), ticket.Status)
+		ticketList = append(ticketList, ticket)
+	}
+	return ticketList, nil
+}
+
+// ledgerAccount keys the ledger entries of this endpoint and user.
+func (a *AzureDevOps) ledgerAccount() ledgerAccount {
+	return ledgerAccount{Backend: azureBackend, Endpoint: a.endpoint, User: a.userName}
+}
+
+func (a *AzureDevOps) GetDayToLog() ([]types.LogWorkStatus, error) {
+	from, to, logworkList := a.days.days()
+
+	fmt.Println("----------------Your week worklog status-------------------")
+	printPeriod(from, to)
+
+	l, err := openLedger(a.ledgerAccount())
+	if err != nil {
+		return nil, fmt.Errorf("error reading worklog ledger: %v", err)
+	}
+
+	fmt.Println("Work logs of the period (local ledger):")
+	l.fillWeek(from, to, logworkList)
+	printWeekStatus(logworkList)
+
+	return logworkList, nil
+}
+
+func (a *AzureDevOps) FillEstimate(ticket []types.Ticket) error {
+	return nil
+}
+
+func (a *AzureDevOps) LogWork(ticket []types.Ticket, logworkList []types.LogWorkStatus) error {
+	logActionList, err := defaultLogWorkAlgorithm(ticket, logworkList, a.allocation)
+	if err != nil {
+		return err
+	}
+	adjust, err := newAdjustRules(a.worklog.AdjustEstimate)
+	if err != nil {
+		return err
+	}
+	comments, err := renderComments(logActionList, a.worklog.Comment)
+	if err != nil {
+		return err
+	}
+
+	printLogActions(logActionList)
+	printRemainingEstimates(logActionList, adjust)
+
+	ok, err := confirmPlan(a.dryRun)
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, raise := range raisedEstimates(logActionList) {
+		if err := a.raiseEstimate(raise.TicketToLog.ID, raise.TimeToLog); err != nil {
+			log.Fatalf("Failed to raise estimate of %s: %v", raise.TicketToLog.ID, err)
+		}
+		fmt.Printf("🔺 Raised estimate of %s by %s\n", raise.TicketToLog.ID, helper.FormatEstimate(raise.TimeToLog))
+	}
+
+	l, err := openLedger(a.ledgerAccount())
+	if err != nil {
+		return fmt.Errorf("error reading worklog ledger: %v", err)
+	}
+
+	run := adjust.newRun()
+	for i, action := range logActionList {
+		// đọc lại work item vì cùng một task có thể được log nhiều ngày
+		workItem, err := a.getWorkItem(action.TicketToLog.ID)
+		if err != nil {
+			log.Printf("⚠️  Cannot fetch work item %s: %v\n", action.TicketToLog.ID, err)
+			continue
+		}
+
+		hours := secondsToHours(action.TimeToLog)
+		// new / manual chỉ áp dụng một lần cho mỗi ticket
+		remaining := secondsToHours(run.next(action.TicketToLog.ID).apply(hoursToSeconds(workItem.Fields.RemainingWork), action.TimeToLog))
+
+		operations := []azurePatchOperation{
+			{Op: "add", Path: "/fields/" + azureCompleteField, Value: workItem.Fields.CompletedWork + hours},
+			{Op: "add", Path: "/fields/" + azureRemainField, Value: remaining},
+		}
+		// Azure DevOps không có worklog, comment được ghi vào discussion của work item
+		if comments[i] != "" {
+			operations = append(operations, azurePatchOperation{Op: "add", Path: "/fields/" + azureHistoryField,
+				Value: fmt.Sprintf("%s logged on %s<br>%s", helper.FormatEstimate(action.TimeToLog), action.DateToLog.Format(time.DateTime),
+					strings.ReplaceAll(html.EscapeString(comments[i]), "\n", "<br>"))})
+		}
+		err = a.updateWorkItem(action.TicketToLog.ID, operations)
+		if err != nil {
+			log.Fatalf("Failed to log work: %v", err)
+		}
+
+		l.add(action.TicketToLog.ID, action.DateToLog, action.TimeToLog)
+		// work đã được log, không lưu được ledger thì tuần sau sẽ log trùng
+		if err := l.save(); err != nil {
+			return fmt.Errorf("work logged to %s but the worklog ledger cannot be saved: %v", action.TicketToLog.ID, err)
+		}
+
+		fmt.Printf("Work logged to work item %s: %s successfully.\n", action.TicketToLog.ID, action.TicketToLog.Summary)
+	}
+
+	return nil
+}
+
+// raiseEstimate grows the original estimate and remaining work of a task by seconds.
+func (a *AzureDevOps) raiseEstimate(id string, seconds int64) error {
+	workItem, err := a.getWorkItem(id)
+	if err != nil {
+		return err
+	}
+
+	hours := secondsToHours(seconds)
+	return a.updateWorkItem(id, []azurePatchOperation{
+		{Op: "add", Path: "/fields/" + azureOriginalField, Value: workItem.Fields.OriginalEstimate + hours},
+		{Op: "add", Path: "/fields/" + azureRemainField, Value: workItem.Fields.RemainingWork + hours},
+	})
+}
+
+// GetTicketToEst fetches the user's open tasks and, for tasks without an original estimate,
+// searches the project for tasks with a similar title that have one.
+func (a *AzureDevOps) GetTicketToEst() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket need to estimate (searching whole project)-------------------")
+
+	rules, err := newEstimateRules(a.estimation.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	wiql := `SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project AND [System.WorkItemType] = 'Task' AND [System.AssignedTo] = @Me AND [System.State] NOT IN ('Closed', 'Done', 'Removed') ORDER BY [System.CreatedDate] DESC`
+	workItems, err := a.queryWorkItems(wiql, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user work items: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
+	for i := range workItems {
+		ticketList = append(ticketList, workItems[i].toTicket())
+	}
+
+	fmt.Printf("Fetched %d tickets assigned to %s\n", len(ticketList), a.userName)
+
+	fmt.Println("\n----------------Auto-fill estimate by searching-------------------")
+
+	suggestions := []suggestion{}
+	for idx := range ticketList {
+		t := &ticketList[idx]
+		if t.Est > 0 {
+			continue
+		}
+
+		// rule trong config được ưu tiên trước khi tìm theo tiêu đề
+		if rule := matchEstimateRule(rules, *t); rule != nil {
+			applyEstimateRule(t, rule)
+			continue
+		}
+
+		fmt.Printf("Searching matches for: %s (%s)\n", t.ID, t.Summary)
+
+		keywords := helper.ExtractKeywords(t.Summary, 3)
+		if len(keywords) == 0 {
+			fmt.Printf(" ⚠️  No useful keywords found for %s, skipping\n", t.ID)
+			continue
+		}
+
+		clauses := []string{}
+		for _, kw := range keywords {
+			clauses = append(clauses, fmt.Sprintf("[System.Title] CONTAINS '%s'", strings.ReplaceAll(kw, "'", "''")))
+		}
+		wiqlSearch := fmt.Sprintf(`SELECT [System.Id] FROM WorkItems WHERE [System.TeamProject] = @project AND [System.WorkItemType] = 'Task' AND [%s] > 0 AND (%s) ORDER BY [System.CreatedDate] DESC`, azureOriginalField, strings.Join(clauses, " OR "))
+
+		candidates, err := a.queryWorkItems(wiqlSearch, 500)
+		if err != nil {
+			log.Printf(" ⚠️  Error searching Azure DevOps for %s: %v\n", t.ID, err)
+			continue
+		}
+
+		if len(candidates) == 0 {
+			fmt.Printf(" ❌  No candidates found in Azure DevOps for %s\n", t.ID)
+			continue
+		}
+
+		candidateTickets := []types.Ticket{}
+		for i := range candidates {
+			candidateTickets = append(candidateTickets, candidates[i].toTicket())
+		}
+		if s, ok := suggestByTitle(ticketList, idx, candidateTickets); ok {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	if err := reviewSuggestions(ticketList, suggestions, a.yes, autoAcceptScore(a.estimation)); err != nil {
+		return nil, err
+	}
+
+	return ticketList, nil
+}
+
+func (a *AzureDevOps) AddEstForTicket(ticketList []types.Ticket) error {
+	fmt.Println("\n----------------Updating estimate to Azure DevOps-------------------")
+
+	for _, t := range ticketList {
+		if t.Est <= 0 {
+			continue
+		}
+
+		workItem, err := a.getWorkItem(t.ID)
+		if err != nil {
+			fmt.Printf(" ⚠️  Cannot fetch work item %s: %v\n", t.ID, err)
+			continue
+		}
+
+		if workItem.Fields.OriginalEstimate > 0 {
+			fmt.Printf("⏭️ %s đã có estimate (%s), bỏ qua\n", t.ID, helper.FormatEstimate(hoursToSeconds(workItem.Fields.OriginalEstimate)))
+			continue
+		}
+
+		operations := []azurePatchOperation{
+			{Op: "add", Path: "/fields/" + azureOriginalField, Value: secondsToHours(t.Est)},
+		}
+		if workItem.Fields.RemainingWork == 0 {
+			operations = append(operations, azurePatchOperation{Op: "add", Path: "/fields/" + azureRemainField, Value: secondsToHours(t.Est)})
+		}
+		if t.EstimateSource != "" {
+			// nguồn của estimate ghi vào Discussion, Boards không có comment riêng
+			operations = append(operations, azurePatchOperation{Op: "add", Path: "/fields/" + azureHistoryField, Value: html.EscapeString(estimateNote(t))})
+		}
+
+		if err := a.updateWorkItem(t.ID, operations); err != nil {
+			fmt.Printf("❌Update fail %s (%s): %v\n", t.ID, t.Summary, err)
+			continue
+		}
+
+		fmt.Printf("✅ Updated estimate %s -> %s (%s)\n", t.ID, helper.FormatEstimate(t.Est), t.EstimateSource)
+	}
+
+	return nil
+}
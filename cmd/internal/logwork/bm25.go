@@ -0,0 +1,88 @@
+package logwork
+
+import (
+	"math"
+)
+
+// Okapi BM25 parameters, the usual defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type posting struct {
+	doc int
+	tf  int
+}
+
+// bm25Index ranks documents, given as token lists, against a query with Okapi BM25.
+type bm25Index struct {
+	postings  map[string][]posting
+	lengths   []int
+	avgLength float64
+}
+
+func newBM25Index(docs [][]string) *bm25Index {
+	x := &bm25Index{postings: map[string][]posting{}, lengths: make([]int, len(docs))}
+
+	total := 0
+	for i, doc := range docs {
+		tf := map[string]int{}
+		for _, term := range doc {
+			tf[term]++
+		}
+		for term, n := range tf {
+			x.postings[term] = append(x.postings[term], posting{doc: i, tf: n})
+		}
+		x.lengths[i] = len(doc)
+		total += len(doc)
+	}
+	if len(docs) > 0 {
+		x.avgLength = float64(total) / float64(len(docs))
+	}
+
+	return x
+}
+
+func (x *bm25Index) idf(term string) float64 {
+	n, df := float64(len(x.lengths)), float64(len(x.postings[term]))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+func (x *bm25Index) termScore(idf float64, tf int, length int) float64 {
+	norm := 1.0
+	if x.avgLength > 0 {
+		norm = 1 - bm25B + bm25B*float64(length)/x.avgLength
+	}
+	return idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+}
+
+// search returns the score of every document sharing a term with the query.
+func (x *bm25Index) search(query []string) map[int]float64 {
+	scores := map[int]float64{}
+	for term := range uniqueTerms(query) {
+		idf := x.idf(term)
+		for _, p := range x.postings[term] {
+			scores[p.doc] += x.termScore(idf, p.tf, x.lengths[p.doc])
+		}
+	}
+	return scores
+}
+
+// selfScore is the score a document made of the query itself would get, used to bring the
+// scores of a query to [0, 1].
+func (x *bm25Index) selfScore(query []string) float64 {
+	score := 0.0
+	for term, tf := range uniqueTerms(query) {
+		score += x.termScore(x.idf(term), tf, len(query))
+	}
+	return score
+}
+
+func uniqueTerms(tokens []string) map[string]int {
+	tf := map[string]int{}
+	for _, t := range tokens {
+		tf[t]++
+	}
+	return tf
+}
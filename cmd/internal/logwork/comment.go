@@ -0,0 +1,171 @@
+package logwork
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// defaultCommentTemplate is used for the tickets without a configured template.
+const defaultCommentTemplate = `{{.Summary}}{{range .Evidence}}
+- {{.}}{{end}}`
+
+// commentData is what a worklog comment template can use.
+type commentData struct {
+	TicketID string
+	Summary  string
+	Project  string
+	Date     time.Time
+	Duration string
+	Seconds  int64
+	Source   string
+	RunID    string
+	Evidence []string
+}
+
+// commentTemplates renders worklog comments from the templates configured per ticket,
+// project or "*".
+type commentTemplates struct {
+	templates map[string]*template.Template
+	runID     string
+}
+
+// newRunID identifies one logwork run, it ends up in the comments of its worklogs.
+func newRunID() string {
+	return time.Now().Format("20060102-150405")
+}
+
+func newCommentTemplates(config map[string]string, runID string) (commentTemplates, error) {
+	c := commentTemplates{templates: map[string]*template.Template{}, runID: runID}
+
+	if _, ok := config["*"]; !ok {
+		c.templates["*"] = template.Must(template.New("*").Parse(defaultCommentTemplate))
+	}
+	for key, text := range config {
+		tmpl, err := template.New(key).Parse(text)
+		if err != nil {
+			return c, fmt.Errorf("invalid comment template for %s: %v", key, err)
+		}
+		c.templates[key] = tmpl
+	}
+
+	return c, nil
+}
+
+// render returns the comment of a worklog.
+func (c commentTemplates) render(action types.LogAction) (string, error) {
+	tmpl, _ := ticketLookup(c.templates, action.TicketToLog.ID)
+
+	project := action.TicketToLog.Project
+	if project == "" {
+		project, _, _ = strings.Cut(action.TicketToLog.ID, "-")
+	}
+
+	evidence := []string{}
+	for _, e := range action.Evidence {
+		if e = strings.TrimSpace(e); e != "" {
+			evidence = append(evidence, e)
+		}
+	}
+
+	var out bytes.Buffer
+	err := tmpl.Execute(&out, commentData{
+		TicketID: action.TicketToLog.ID,
+		Summary:  action.TicketToLog.Summary,
+		Project:  project,
+		Date:     action.DateToLog,
+		Duration: helper.FormatEstimate(action.TimeToLog),
+		Seconds:  action.TimeToLog,
+		Source:   action.Source,
+		RunID:    c.runID,
+		Evidence: evidence,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot render comment of %s: %v", action.TicketToLog.ID, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// renderComments renders the comment of every worklog of the plan in one run, so a broken
+// template is reported before anything is written.
+func renderComments(logActionList []types.LogAction, config map[string]string) ([]string, error) {
+	templates, err := newCommentTemplates(config, newRunID())
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]string, len(logActionList))
+	for i := range logActionList {
+		if comments[i], err = templates.render(logActionList[i]); err != nil {
+			return nil, err
+		}
+	}
+	return comments, nil
+}
+
+// adfDocument turns a plain text comment into an Atlassian Document Format document: a
+// paragraph per line, consecutive "- " lines as a bullet list.
+func adfDocument(text string) map[string]interface{} {
+	textNode := func(s string) []interface{} {
+		return []interface{}{map[string]interface{}{"type": "text", "text": s}}
+	}
+
+	content := []interface{}{}
+	var list []interface{}
+	flush := func() {
+		if len(list) > 0 {
+			content = append(content, map[string]interface{}{"type": "bulletList", "content": list})
+			list = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if item, ok := strings.CutPrefix(line, "- "); ok && item != "" {
+			list = append(list, map[string]interface{}{
+				"type":    "listItem",
+				"content": []interface{}{map[string]interface{}{"type": "paragraph", "content": textNode(item)}},
+			})
+			continue
+		}
+		flush()
+		// ADF không cho phép text node rỗng
+		if line != "" {
+			content = append(content, map[string]interface{}{"type": "paragraph", "content": textNode(line)})
+		}
+	}
+	flush()
+
+	return map[string]interface{}{"type": "doc", "version": 1, "content": content}
+}
+
+// adfText returns the plain text of a rich text field value: REST v3 returns it as an ADF
+// document, REST v2 as a string. Block nodes end with a newline.
+func adfText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+		var b strings.Builder
+		content, _ := v["content"].([]interface{})
+		for _, child := range content {
+			b.WriteString(adfText(child))
+		}
+		switch v["type"] {
+		case "paragraph", "heading", "listItem", "codeBlock":
+			b.WriteString("\n")
+		case "hardBreak":
+			return "\n"
+		}
+		return b.String()
+	}
+	return ""
+}
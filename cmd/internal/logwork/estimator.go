@@ -0,0 +1,321 @@
+package logwork
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// Estimators selectable with est --estimator.
+const (
+	// estimatorIndex ranks the local history index with BM25 and the context score
+	estimatorIndex = "index"
+	// estimatorV1 searches Jira by summary keywords and scores the summary only
+	estimatorV1 = "v1"
+	// estimatorV2 searches the project / parent of the ticket and adds the context score
+	estimatorV2 = "v2"
+)
+
+// Default match thresholds of the estimators, Estimation.MatchThreshold overrides them.
+var defaultMatchThresholds = map[string]float64{
+	estimatorIndex: 0.7,
+	estimatorV1:    0.95,
+	estimatorV2:    0.90,
+}
+
+// Default context factor weights, they add up to 1.
+var defaultContextWeights = map[string]float64{
+	"project": 0.4,
+	"parent":  0.3,
+	"labels":  0.2,
+	"recency": 0.1,
+}
+
+const defaultDecayDays = 180
+
+// candidateLimit caps the candidates of one Jira search
+const candidateLimit = 500
+
+var candidateFields = []string{"summary", "issuetype", "status", "project", "labels", "parent", "assignee", "created", "resolutiondate", "timeoriginalestimate", "timespent"}
+
+// Estimator finds the issues most similar to a ticket, whose estimate or time spent is then
+// suggested for it.
+type Estimator interface {
+	// Similar returns the k best matches for the ticket, best first.
+	Similar(t types.Ticket, k int) ([]historyMatch, error)
+	// Threshold is the score the best match needs for a suggestion.
+	Threshold() float64
+}
+
+// scoring combines the text and context similarity of a candidate.
+type scoring struct {
+	textWeight    float64
+	contextWeight float64
+	// context are the weights of the context factors: project, parent, labels, recency
+	context   map[string]float64
+	decayDays float64
+	threshold float64
+}
+
+func newScoring(estimator string, config types.EstimationConfig) (scoring, error) {
+	s := scoring{textWeight: 0.6, contextWeight: 0.4, context: map[string]float64{}, decayDays: defaultDecayDays}
+
+	if config.TextWeight != 0 || config.ContextWeight != 0 {
+		s.textWeight, s.contextWeight = config.TextWeight, config.ContextWeight
+	}
+	for factor, weight := range defaultContextWeights {
+		s.context[factor] = weight
+	}
+	for factor, weight := range config.ContextWeights {
+		if _, ok := defaultContextWeights[factor]; !ok {
+			return s, fmt.Errorf("invalid context factor %q, expected project, parent, labels or recency", factor)
+		}
+		s.context[factor] = weight
+	}
+	if config.DecayDays > 0 {
+		s.decayDays = float64(config.DecayDays)
+	}
+
+	s.threshold = defaultMatchThresholds[estimator]
+	if config.MatchThreshold > 0 {
+		s.threshold = config.MatchThreshold
+	}
+
+	return s, nil
+}
+
+// contextScore tính điểm ngữ cảnh dựa trên project, parent, labels và thời gian, trong [0, 1]
+func (s scoring) contextScore(t, c types.Ticket) float64 {
+	contextScore := 0.0
+
+	// 1) Project matching
+	if t.Project != "" && t.Project == c.Project {
+		contextScore += s.context["project"]
+	}
+
+	// 2) Parent (epic/sub-task) matching
+	if t.Parent != "" && t.Parent == c.Parent {
+		contextScore += s.context["parent"]
+	}
+
+	// 3) Labels matching (tỷ lệ labels chung)
+	if len(t.Labels) > 0 && len(c.Labels) > 0 {
+		commonLabels := len(intersect(t.Labels, c.Labels))
+		totalLabels := float64(len(t.Labels) + len(c.Labels) - commonLabels)
+		if totalLabels > 0 {
+			contextScore += float64(commonLabels) / totalLabels * s.context["labels"]
+		}
+	}
+
+	// 4) Thời gian: ưu tiên ticket tạo gần nhau, giảm tuyến tính về 0 sau decayDays
+	created, cCreated := time.Time(t.Created), time.Time(c.Created)
+	if !created.IsZero() && !cCreated.IsZero() {
+		timeDiff := math.Abs(created.Sub(cCreated).Hours() / 24)
+		contextScore += s.context["recency"] * math.Max(0, 1-timeDiff/s.decayDays)
+	}
+
+	// Chuẩn hóa contextScore về [0, 1]
+	return math.Min(contextScore, 1.0)
+}
+
+func (s scoring) match(t types.Ticket, issue historyIssue, text float64) historyMatch {
+	m := historyMatch{issue: issue, text: text, context: s.contextScore(t, issue.ticket())}
+	m.score = s.textWeight*m.text + s.contextWeight*m.context
+	return m
+}
+
+// intersect trả về các phần tử chung của hai slice string
+func intersect(a, b []string) []string {
+	set := make(map[string]bool)
+	var result []string
+	for _, item := range a {
+		set[item] = true
+	}
+	for _, item := range b {
+		if set[item] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// indexEstimator ranks the local history index.
+type indexEstimator struct {
+	index   *historyIndex
+	scoring scoring
+	// backtest only takes the issues resolved before the ticket was created
+	backtest bool
+}
+
+func (e indexEstimator) Similar(t types.Ticket, k int) ([]historyMatch, error) {
+	before := time.Time{}
+	if e.backtest {
+		before = time.Time(t.Created)
+	}
+	return e.index.similar(t, k, e.scoring, before), nil
+}
+
+func (e indexEstimator) Threshold() float64 {
+	return e.scoring.threshold
+}
+
+// jqlEstimator searches Jira for every ticket, by summary keywords, and scores the summaries
+// with Jaro-Winkler. Offline the local history stands in for the search.
+type jqlEstimator struct {
+	j       *Jira
+	scoring scoring
+	// withContext restricts the search to the project / parent and adds the context score (v2)
+	withContext bool
+	// backtest only takes the issues resolved before the ticket was created
+	backtest bool
+	// fromSpent only takes resolved issues, the time spent on open ones is partial
+	fromSpent bool
+}
+
+func (e jqlEstimator) Similar(t types.Ticket, k int) ([]historyMatch, error) {
+	// Tạo keywords từ summary đã bỏ stopword, từ >= 3 ký tự ("lỗi" bỏ dấu còn 3) để tăng độ đặc trưng
+	keywords := helper.ExtractKeywords(t.Summary, 3)
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+
+	// Build JQL: tìm các issue có summary chứa ít nhất một keyword và có estimate (hoặc time spent ở mode spent)
+	jql := fmt.Sprintf("(%s)", helper.BuildJQLForKeywords(keywords))
+	if e.fromSpent {
+		jql += " AND resolution IS NOT EMPTY AND timespent IS NOT EMPTY"
+	} else {
+		jql += " AND timeoriginalestimate IS NOT EMPTY"
+	}
+	filters := []func(historyIssue) bool{}
+	if e.backtest {
+		// JQL chỉ so sánh theo phút, lọc lại chính xác ở dưới
+		created := time.Time(t.Created)
+		jql += fmt.Sprintf(` AND resolved <= "%s"`, created.Format("2006/01/02 15:04"))
+		filters = append(filters, func(c historyIssue) bool { return !c.Resolved.IsZero() && c.Resolved.Before(created) })
+	}
+	if e.fromSpent {
+		filters = append(filters, func(c historyIssue) bool { return !c.Resolved.IsZero() })
+	}
+	if e.withContext && (t.Project != "" || t.Parent != "") {
+		// chỉ lọc theo những gì ticket có, project rỗng sẽ làm JQL không hợp lệ
+		scopes := []string{}
+		if t.Project != "" {
+			scopes = append(scopes, fmt.Sprintf("project = %s", t.Project))
+		}
+		if t.Parent != "" {
+			scopes = append(scopes, fmt.Sprintf("parent = %s", t.Parent))
+		}
+		jql += " AND (" + strings.Join(scopes, " OR ") + ")"
+		filters = append(filters, func(c historyIssue) bool {
+			return (t.Project != "" && c.Project == t.Project) || (t.Parent != "" && c.Parent == t.Parent)
+		})
+	}
+	jql += " ORDER BY created DESC"
+
+	candidates, err := e.j.searchCandidates(jql, keywords)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []historyMatch{}
+	for _, c := range candidates {
+		if c.Key == t.ID || !c.predicts(e.fromSpent) || !matchesAll(filters, c) {
+			continue
+		}
+		text := helper.StringSimilarity(t.Summary, c.Summary)
+		if e.withContext {
+			matches = append(matches, e.scoring.match(t, c, text))
+		} else {
+			matches = append(matches, historyMatch{issue: c, text: text, score: text})
+		}
+	}
+
+	sortMatches(matches)
+	return matches[:min(k, len(matches))], nil
+}
+
+func (e jqlEstimator) Threshold() float64 {
+	return e.scoring.threshold
+}
+
+func matchesAll(filters []func(historyIssue) bool, c historyIssue) bool {
+	for _, filter := range filters {
+		if !filter(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchCandidates runs a candidate query on Jira. Offline, the issues of the local history
+// whose summary has one of the keywords, diacritics folded, are used instead, the caller
+// filters them like the rest of the JQL.
+func (j *Jira) searchCandidates(jql string, keywords []string) ([]historyIssue, error) {
+	candidates := []historyIssue{}
+
+	if !j.offline {
+		issues, err := j.search(jql, candidateFields, candidateLimit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range issues {
+			candidates = append(candidates, newHistoryIssue(&issues[i]))
+		}
+		return candidates, nil
+	}
+
+	store, err := j.localStore()
+	if err != nil {
+		return nil, err
+	}
+	issues, err := store.history()
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		summary := helper.FoldDiacritics(issue.Summary)
+		for _, keyword := range keywords {
+			if strings.Contains(summary, helper.FoldDiacritics(keyword)) {
+				candidates = append(candidates, issue)
+				break
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// newEstimator returns the estimator selected by Estimation.Estimator, the history index by
+// default. A backtest estimator only uses the issues resolved before the ticket was created.
+func (j *Jira) newEstimator(backtest bool) (Estimator, error) {
+	name := j.estimation.Estimator
+	if name == "" {
+		name = estimatorIndex
+	}
+	if _, ok := defaultMatchThresholds[name]; !ok {
+		return nil, fmt.Errorf("invalid estimator %q, expected index, v1 or v2", name)
+	}
+
+	s, err := newScoring(name, j.estimation)
+	if err != nil {
+		return nil, err
+	}
+
+	fromSpent := j.estimation.Mode == estimateFromSpent
+	switch name {
+	case estimatorV1:
+		return jqlEstimator{j: j, scoring: s, backtest: backtest, fromSpent: fromSpent}, nil
+	case estimatorV2:
+		return jqlEstimator{j: j, scoring: s, withContext: true, backtest: backtest, fromSpent: fromSpent}, nil
+	default:
+		index, err := j.historyIndex()
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("History index: %d resolved issues\n", len(index.issues))
+		return indexEstimator{index: index, scoring: s, backtest: backtest}, nil
+	}
+}
@@ -0,0 +1,71 @@
+package logwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// jqlSearches runs one Similar search of e against a Jira Server stand-in and returns the JQL sent.
+func jqlSearches(t *testing.T, e jqlEstimator, ticket types.Ticket) []string {
+	searches := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches = append(searches, r.URL.Query().Get("jql"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"startAt": 0, "maxResults": 50, "total": 0, "issues": []}`))
+	}))
+	defer server.Close()
+
+	client, err := jira.NewClient(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloud := false
+	e.j = &Jira{client: client, cloud: &cloud}
+	if _, err := e.Similar(ticket, 3); err != nil {
+		t.Fatalf("Similar: %v", err)
+	}
+	return searches
+}
+
+func TestJQLEstimatorSpentModeOnlyResolvedIssues(t *testing.T) {
+	ticket := types.Ticket{ID: "APP-9", Summary: "Login page layout", Project: "APP"}
+
+	searches := jqlSearches(t, jqlEstimator{fromSpent: true}, ticket)
+	if len(searches) != 1 || !strings.Contains(searches[0], "resolution IS NOT EMPTY AND timespent IS NOT EMPTY") || strings.Contains(searches[0], "timeoriginalestimate") {
+		t.Errorf("spent mode JQL = %v, want resolved issues with time spent, estimated or not", searches)
+	}
+	searches = jqlSearches(t, jqlEstimator{}, ticket)
+	if len(searches) != 1 || strings.Contains(searches[0], "resolution") || !strings.Contains(searches[0], "timeoriginalestimate IS NOT EMPTY") {
+		t.Errorf("estimate mode JQL = %v, want open issues with an estimate too", searches)
+	}
+}
+
+func TestJQLEstimatorScopeSkipsEmptyProject(t *testing.T) {
+	for _, tc := range []struct {
+		ticket types.Ticket
+		scope  string
+	}{
+		{types.Ticket{Project: "APP"}, "AND (project = APP)"},
+		{types.Ticket{Project: "APP", Parent: "APP-1"}, "AND (project = APP OR parent = APP-1)"},
+		{types.Ticket{Parent: "APP-1"}, "AND (parent = APP-1)"},
+		{types.Ticket{}, ""},
+	} {
+		tc.ticket.ID, tc.ticket.Summary = "APP-9", "Login page layout"
+		searches := jqlSearches(t, jqlEstimator{withContext: true}, tc.ticket)
+		if len(searches) != 1 {
+			t.Fatalf("searches = %v", searches)
+		}
+		jql := searches[0]
+		if tc.scope != "" && !strings.Contains(jql, tc.scope) {
+			t.Errorf("JQL = %s, want scope %q", jql, tc.scope)
+		}
+		if tc.scope == "" && (strings.Contains(jql, "project") || strings.Contains(jql, "parent")) {
+			t.Errorf("JQL = %s, want no scope", jql)
+		}
+	}
+}
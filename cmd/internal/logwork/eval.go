@@ -0,0 +1,247 @@
+package logwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+)
+
+// evalThresholds are the match thresholds est eval reports the coverage of, next to the
+// threshold of the estimator.
+var evalThresholds = []float64{0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.95}
+
+// evalBuckets are the lower bounds of the score buckets of the calibration table.
+var evalBuckets = []float64{0, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// evalHitRatio is how close to the time spent a prediction must be to count as a hit (±25%)
+const evalHitRatio = 0.25
+
+// evalResult is the replayed suggestion of one resolved issue.
+type evalResult struct {
+	key string
+	// score is the score of the best match, 0 when nothing matched
+	score float64
+	// predicted is 0 when no prediction could be made
+	predicted int64
+	// spent is the time actually spent, estimate the original estimate of the issue
+	spent    int64
+	estimate int64
+}
+
+// evalErrors are the errors of a set of predictions against the time spent.
+type evalErrors struct {
+	n    int
+	mae  float64
+	mape float64
+	// hits is the share of predictions within evalHitRatio of the time spent
+	hits float64
+}
+
+func newEvalErrors(results []evalResult, predicted func(evalResult) int64) evalErrors {
+	e := evalErrors{}
+	for _, r := range results {
+		p := predicted(r)
+		if p <= 0 {
+			continue
+		}
+		diff := math.Abs(float64(p - r.spent))
+		e.n++
+		e.mae += diff
+		e.mape += diff / float64(r.spent)
+		if diff <= evalHitRatio*float64(r.spent) {
+			e.hits++
+		}
+	}
+	if e.n > 0 {
+		e.mae /= float64(e.n)
+		e.mape /= float64(e.n)
+		e.hits /= float64(e.n)
+	}
+	return e
+}
+
+func (e evalErrors) String() string {
+	if e.n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("MAE %-10s MAPE %6.1f%%  within ±%d%%: %5.1f%%",
+		helper.FormatEstimate(int64(math.Round(e.mae))), e.mape*100, int(evalHitRatio*100), e.hits*100)
+}
+
+// EvaluateEstimator replays the estimator against resolved issues (see evalTargets): every
+// issue of the project (all projects if empty) resolved since the date is predicted as if it
+// was new, from the issues resolved before it was created only, and the predictions are
+// compared to the time actually spent. The BM25 term weights still come from the whole
+// history, which barely moves the scores.
+func (j *Jira) EvaluateEstimator(project string, since time.Time) error {
+	mode := j.estimation.Mode
+	if mode == "" {
+		mode = estimateFromEstimate
+	}
+	if mode != estimateFromEstimate && mode != estimateFromSpent {
+		return fmt.Errorf("invalid estimation mode %q, expected estimate or spent", mode)
+	}
+	topK := j.estimation.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+
+	estimator, err := j.newEstimator(true)
+	if err != nil {
+		return err
+	}
+	targets, err := j.evalTargets(project, since)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println("No resolved issue with time spent to evaluate, check --project / --since and the history (Estimation.HistoryJQL / HistoryDays)")
+		return nil
+	}
+	sort.Slice(targets, func(a, b int) bool { return targets[a].Resolved.Before(targets[b].Resolved) })
+
+	name := j.estimation.Estimator
+	if name == "" {
+		name = estimatorIndex
+	}
+	fmt.Printf("🧪 Replaying %s (%s mode) on %d resolved issues\n", name, mode, len(targets))
+
+	results := make([]evalResult, 0, len(targets))
+	for _, target := range targets {
+		r := evalResult{key: target.Key, spent: target.Spent, estimate: target.Estimate}
+
+		// không để lộ estimate / thời gian thật của chính issue đang dự đoán
+		t := target.ticket()
+		t.Est, t.EstimatedLogged = 0, 0
+		matches, err := estimator.Similar(t, topK)
+		if err != nil {
+			return fmt.Errorf("cannot replay %s: %v", target.Key, err)
+		}
+		if len(matches) > 0 {
+			r.score = matches[0].score
+			if mode == estimateFromSpent {
+				// ratio cá nhân tính trên toàn bộ lịch sử nên bỏ qua khi replay
+				if p, ok := predictFromSpent(matches, 0, nil); ok {
+					r.predicted = p.seconds
+				}
+			} else {
+				r.predicted = matches[0].issue.Estimate
+			}
+		}
+		results = append(results, r)
+	}
+
+	printEvaluation(results, estimator.Threshold())
+	return nil
+}
+
+// evalTargets returns the issues of project resolved since with time spent. With a project
+// they are fetched from Jira, the history only covers the user's projects over HistoryDays.
+// Offline or without a project the history is used, with a warning for what it misses.
+func (j *Jira) evalTargets(project string, since time.Time) ([]historyIssue, error) {
+	keep := func(issue historyIssue) bool {
+		return (project == "" || issue.Project == project) && !issue.Resolved.Before(since) && issue.Spent > 0
+	}
+	targets := []historyIssue{}
+
+	if project != "" && !j.offline {
+		jql := fmt.Sprintf(`project = %s AND resolution IS NOT EMPTY AND timespent > 0 AND resolved >= "%s"`, project, since.Format("2006/01/02 15:04"))
+		issues, err := j.search(jql, historyFields, historyLimit)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch the resolved issues of %s: %v", project, err)
+		}
+		for i := range issues {
+			if issue := newHistoryIssue(&issues[i]); keep(issue) {
+				targets = append(targets, issue)
+			}
+		}
+		return targets, nil
+	}
+
+	store, err := j.localStore()
+	if err != nil {
+		return nil, err
+	}
+	history, err := store.history()
+	if err != nil {
+		return nil, err
+	}
+
+	days := j.estimation.HistoryDays
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+	if oldest := time.Now().AddDate(0, 0, -days); since.Before(oldest) {
+		fmt.Printf(" ⚠️  --since %s is older than the history (HistoryDays %d), issues resolved before %s are not replayed\n",
+			since.Format(time.DateOnly), days, oldest.Format(time.DateOnly))
+	}
+	inHistory := false
+	for _, issue := range history {
+		inHistory = inHistory || issue.Project == project
+		if keep(issue) {
+			targets = append(targets, issue)
+		}
+	}
+	if project != "" && !inHistory {
+		fmt.Printf(" ⚠️  %s is outside the estimation history (Estimation.HistoryJQL), run without --offline to fetch it\n", project)
+	}
+	return targets, nil
+}
+
+func printEvaluation(results []evalResult, threshold float64) {
+	fmt.Println("\n----------------Estimator backtest-------------------")
+	fmt.Printf("Original estimates:  %s\n", newEvalErrors(results, func(r evalResult) int64 { return r.estimate }))
+	fmt.Printf("Any match:           %s\n", newEvalErrors(results, func(r evalResult) int64 { return r.predicted }))
+
+	thresholds := append([]float64{}, evalThresholds...)
+	if !containsFloat(thresholds, threshold) {
+		thresholds = append(thresholds, threshold)
+		sort.Float64s(thresholds)
+	}
+
+	fmt.Println("\nCoverage by threshold (predictions whose best match scores at least the threshold)")
+	fmt.Printf("%-10s %-18s %s\n", "Threshold", "Coverage", "Errors")
+	for _, th := range thresholds {
+		covered := []evalResult{}
+		for _, r := range results {
+			if r.predicted > 0 && r.score >= th {
+				covered = append(covered, r)
+			}
+		}
+		marker := ""
+		if th == threshold {
+			marker = "  ← current"
+		}
+		fmt.Printf("%-10.2f %5.1f%% (%4d/%-4d) %s%s\n", th, 100*float64(len(covered))/float64(len(results)),
+			len(covered), len(results), newEvalErrors(covered, func(r evalResult) int64 { return r.predicted }), marker)
+	}
+
+	fmt.Println("\nCalibration (are higher scores more accurate?)")
+	fmt.Printf("%-12s %-6s %s\n", "Score", "Count", "Errors")
+	for i, low := range evalBuckets {
+		// bucket cuối gồm cả score = 1
+		high, last := 1.0, i == len(evalBuckets)-1
+		if !last {
+			high = evalBuckets[i+1]
+		}
+		bucket := []evalResult{}
+		for _, r := range results {
+			if r.predicted > 0 && r.score >= low && (r.score < high || last) {
+				bucket = append(bucket, r)
+			}
+		}
+		fmt.Printf("%.2f-%.2f    %-6d %s\n", low, high, len(bucket), newEvalErrors(bucket, func(r evalResult) int64 { return r.predicted }))
+	}
+}
+
+func containsFloat(values []float64, v float64) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
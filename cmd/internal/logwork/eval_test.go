@@ -0,0 +1,43 @@
+package logwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+func TestEvalTargetsFetchesTheProject(t *testing.T) {
+	searches := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches = append(searches, r.URL.Query().Get("jql"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"startAt": 0, "maxResults": 50, "total": 2, "issues": [
+			{"key": "ABC-1", "fields": {"summary": "Login page", "project": {"key": "ABC"}, "resolutiondate": "2024-03-01T10:00:00.000+0000", "timespent": 7200, "timeoriginalestimate": 3600}},
+			{"key": "ABC-2", "fields": {"summary": "Logout", "project": {"key": "ABC"}, "resolutiondate": "2024-03-02T10:00:00.000+0000", "timespent": 0}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client, err := jira.NewClient(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloud := false
+	j := &Jira{client: client, cloud: &cloud}
+
+	// ABC không nằm trong history của user nhưng vẫn được lấy thẳng từ Jira
+	targets, err := j.evalTargets("ABC", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("evalTargets: %v", err)
+	}
+	if len(searches) != 1 || !strings.Contains(searches[0], "project = ABC") || !strings.Contains(searches[0], `resolved >= "2024/01/01 00:00"`) {
+		t.Errorf("searches = %v, want the resolved issues of ABC since 2024-01-01", searches)
+	}
+	if len(targets) != 1 || targets[0].Key != "ABC-1" || targets[0].Spent != 7200 {
+		t.Errorf("targets = %+v, want ABC-1 only, the issue with time spent", targets)
+	}
+}
@@ -0,0 +1,127 @@
+package logwork
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// hintLabelPrefix marks ticket labels holding allocation hints, e.g. logwork-max-per-day:4h,
+// logwork-weight:2, logwork-pin:2026-10-20_3h or logwork-exclude
+const hintLabelPrefix = "logwork-"
+
+var (
+	hintSeparator = regexp.MustCompile(`[;\n]+`)
+	hintDate      = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+	hintDuration  = regexp.MustCompile(`(\d+(\.\d+)?[hm])+`)
+	hintKey       = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+)
+
+// ParseHints parses allocation hints such as "max-per-day: 4h; weight: 2; exclude" or
+// "pin: ABC-7 on 2026-10-20 3h". The ticket key of a pin is optional and, when present,
+// must match ticketID.
+func ParseHints(ticketID string, text string) (types.AllocationHints, error) {
+	hints := types.AllocationHints{}
+
+	for _, statement := range hintSeparator.Split(text, -1) {
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(statement, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		switch name {
+		case "exclude":
+			hints.Exclude = true
+		case "weight":
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil || weight <= 0 {
+				return hints, fmt.Errorf("invalid weight %q", value)
+			}
+			hints.Weight = weight
+		case "max-per-day":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return hints, fmt.Errorf("invalid max-per-day %q", value)
+			}
+			hints.MaxPerDay = int64(d.Seconds())
+		case "pin":
+			if key := hintKey.FindString(value); key != "" && key != ticketID {
+				return hints, fmt.Errorf("pin %q is for %s, not %s", value, key, ticketID)
+			}
+			date, err := time.ParseInLocation(time.DateOnly, hintDate.FindString(value), time.Local)
+			if err != nil {
+				return hints, fmt.Errorf("invalid pin date in %q", value)
+			}
+			// bỏ ngày đi trước khi tìm thời lượng, tránh nhầm số trong ngày
+			d, err := time.ParseDuration(hintDuration.FindString(hintDate.ReplaceAllString(value, "")))
+			if err != nil || d <= 0 {
+				return hints, fmt.Errorf("invalid pin duration in %q", value)
+			}
+			hints.Pins = append(hints.Pins, types.Pin{Date: date, TimeToLog: int64(d.Seconds())})
+		default:
+			return hints, fmt.Errorf("unknown hint %q", statement)
+		}
+	}
+
+	return hints, nil
+}
+
+// ApplyHints sets the allocation hints of every ticket from its labels and the local config
+// (local hints override labels), then turns pins into fixed worklogs of their day.
+func ApplyHints(ticket []types.Ticket, logworkList []types.LogWorkStatus, config types.AllocationConfig) error {
+	dayShift, err := newShift(config)
+	if err != nil {
+		return err
+	}
+
+	for i := range ticket {
+		t := &ticket[i]
+
+		for _, label := range t.Labels {
+			if !strings.HasPrefix(label, hintLabelPrefix) {
+				continue
+			}
+			hints, err := ParseHints(t.ID, strings.ReplaceAll(strings.TrimPrefix(label, hintLabelPrefix), "_", " "))
+			if err != nil {
+				return fmt.Errorf("invalid hint label %q on %s: %v", label, t.ID, err)
+			}
+			t.Hints = t.Hints.Merge(hints)
+		}
+
+		if text, ok := config.Hints[t.ID]; ok {
+			hints, err := ParseHints(t.ID, text)
+			if err != nil {
+				return fmt.Errorf("invalid hints for %s in config: %v", t.ID, err)
+			}
+			t.Hints = t.Hints.Merge(hints)
+		}
+
+		for _, pin := range t.Hints.Pins {
+			idx := -1
+			for d := range logworkList {
+				if logworkList[d].Date.Format(time.DateOnly) == pin.Date.Format(time.DateOnly) {
+					idx = d
+				}
+			}
+			if idx < 0 {
+				fmt.Printf(" ⚠️  Pin of %s on %s is outside the days of the run, skipping\n", t.ID, pin.Date.Format(time.DateOnly))
+				continue
+			}
+			logworkList[idx].AddFixed(types.LogAction{
+				TimeToLog:   pin.TimeToLog,
+				DateToLog:   pin.Date.Add(dayShift.start),
+				TicketToLog: *t,
+				Source:      "pin",
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,361 @@
+package logwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Defaults of the estimation history, see types.EstimationConfig.
+const (
+	defaultHistoryDays    = 365
+	defaultHistoryRefresh = 24 * time.Hour
+	// historyLimit caps one history sync
+	historyLimit = 20000
+)
+
+var (
+	historyBucket = []byte("history")
+	// historySyncKey (meta bucket) is when the last history sync started
+	historySyncKey = []byte("historySync")
+	// historyVersionKey (meta bucket) is the historyVersion of the last full history sync
+	historyVersionKey = []byte("historyVersion")
+)
+
+// historyVersion is bumped when the history sync takes more issues, an older history is then
+// downloaded again in full.
+const historyVersion = 2
+
+var historyFields = []string{"summary", "description", "issuetype", "project", "parent", "labels", "assignee", "created", "resolutiondate", "timeoriginalestimate", "timespent"}
+
+// historyIssue is a resolved issue of the estimation history.
+type historyIssue struct {
+	Key         string
+	Summary     string
+	Description string
+	Type        string
+	Project     string
+	Parent      string
+	Labels      []string
+	// Assignee is the account id on Cloud, the user name on Server / Data Center
+	Assignee string
+	Created  time.Time
+	Resolved time.Time
+	Estimate int64
+	Spent    int64
+}
+
+func newHistoryIssue(issue *jira.Issue) historyIssue {
+	h := historyIssue{Key: issue.Key}
+	f := issue.Fields
+	if f == nil {
+		return h
+	}
+
+	h.Summary = f.Summary
+	h.Description = f.Description
+	h.Type = f.Type.Name
+	h.Project = f.Project.Key
+	h.Labels = f.Labels
+	h.Created = time.Time(f.Created)
+	h.Resolved = time.Time(f.Resolutiondate)
+	h.Estimate = int64(f.TimeOriginalEstimate)
+	h.Spent = int64(f.TimeSpent)
+	if f.Parent != nil {
+		h.Parent = f.Parent.Key
+	}
+	h.Assignee = jiraUserID(f.Assignee)
+	return h
+}
+
+func (h historyIssue) ticket() types.Ticket {
+	return types.Ticket{
+		ID:              h.Key,
+		Summary:         h.Summary,
+		Description:     h.Description,
+		Est:             h.Estimate,
+		EstimatedLogged: h.Spent,
+		Type:            h.Type,
+		Project:         h.Project,
+		Labels:          h.Labels,
+		Parent:          h.Parent,
+		Created:         jira.Time(h.Created),
+	}
+}
+
+// predicts tells whether the issue has what the mode predicts from: the time spent when
+// fromSpent, the original estimate otherwise.
+func (h historyIssue) predicts(fromSpent bool) bool {
+	if fromSpent {
+		return h.Spent > 0
+	}
+	return h.Estimate > 0
+}
+
+func (s *store) putHistory(issues []historyIssue) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(historyBucket)
+		for _, issue := range issues {
+			v, err := json.Marshal(issue)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(issue.Key), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *store) history() ([]historyIssue, error) {
+	issues := []historyIssue{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyBucket).ForEach(func(k, v []byte) error {
+			issue := historyIssue{}
+			if err := json.Unmarshal(v, &issue); err != nil {
+				return fmt.Errorf("corrupted cache entry %s: %v", k, err)
+			}
+			issues = append(issues, issue)
+			return nil
+		})
+	})
+	return issues, err
+}
+
+// historySync returns when the history was last synced, zero if never or by an older
+// historyVersion.
+func (s *store) historySync() (time.Time, error) {
+	lastSync, version := time.Time{}, 1
+	err := s.db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if v := meta.Get(historyVersionKey); v != nil {
+			if err := json.Unmarshal(v, &version); err != nil {
+				return err
+			}
+		}
+		if v := meta.Get(historySyncKey); v != nil {
+			return lastSync.UnmarshalText(v)
+		}
+		return nil
+	})
+	if version < historyVersion {
+		return time.Time{}, err
+	}
+	return lastSync, err
+}
+
+// setHistorySync records a history sync, full drops the previous history first.
+func (s *store) setHistorySync(lastSync time.Time, issues []historyIssue, full bool) error {
+	if full {
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			if err := tx.DeleteBucket(historyBucket); err != nil {
+				return err
+			}
+			_, err := tx.CreateBucket(historyBucket)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := s.putHistory(issues); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		v, err := lastSync.MarshalText()
+		if err != nil {
+			return err
+		}
+		meta := tx.Bucket(metaBucket)
+		if err := meta.Put(historySyncKey, v); err != nil {
+			return err
+		}
+		v, _ = json.Marshal(historyVersion)
+		return meta.Put(historyVersionKey, v)
+	})
+}
+
+// historyIndex ranks the resolved issues of the history against a ticket: BM25 over summary
+// and description, combined with the context score by the scoring.
+type historyIndex struct {
+	issues []historyIssue
+	bm25   *bm25Index
+}
+
+// historyMatch is one resolved issue similar to a ticket. text and context are in [0, 1].
+type historyMatch struct {
+	issue   historyIssue
+	text    float64
+	context float64
+	score   float64
+}
+
+// historyTokens are the terms indexed for an issue, the summary counts twice as it says more
+// per word than the description.
+func historyTokens(summary string, description string) []string {
+	tokens := helper.Tokenize(summary)
+	tokens = append(tokens, tokens...)
+	return append(tokens, helper.Tokenize(description)...)
+}
+
+func newHistoryIndex(issues []historyIssue) *historyIndex {
+	docs := make([][]string, len(issues))
+	for i, issue := range issues {
+		docs[i] = historyTokens(issue.Summary, issue.Description)
+	}
+	return &historyIndex{issues: issues, bm25: newBM25Index(docs)}
+}
+
+// similar returns the k issues most similar to the ticket, best first. With a non zero
+// before, only the issues resolved before it are candidates.
+func (x *historyIndex) similar(t types.Ticket, k int, s scoring, before time.Time) []historyMatch {
+	query := historyTokens(t.Summary, t.Description)
+	self := x.bm25.selfScore(query)
+	if self <= 0 {
+		return nil
+	}
+
+	matches := []historyMatch{}
+	for doc, score := range x.bm25.search(query) {
+		if x.issues[doc].Key == t.ID || (!before.IsZero() && !x.issues[doc].Resolved.Before(before)) {
+			continue
+		}
+		matches = append(matches, s.match(t, x.issues[doc], min(score/self, 1)))
+	}
+
+	sortMatches(matches)
+	return matches[:min(k, len(matches))]
+}
+
+// sortMatches orders matches by score, the most recently resolved first on a tie.
+func sortMatches(matches []historyMatch) {
+	sort.Slice(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		return matches[a].issue.Resolved.After(matches[b].issue.Resolved)
+	})
+}
+
+// historyIndex returns the index of the resolved issues the estimation mode can use, synced
+// first when it is older than HistoryRefresh (unless offline).
+func (j *Jira) historyIndex() (*historyIndex, error) {
+	store, err := j.localStore()
+	if err != nil {
+		return nil, err
+	}
+	if !j.offline {
+		if err := j.syncHistory(store); err != nil {
+			return nil, fmt.Errorf("cannot sync the estimation history: %v", err)
+		}
+	}
+
+	issues, err := store.history()
+	if err != nil {
+		return nil, err
+	}
+
+	days := j.estimation.HistoryDays
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+	// lần sync tăng dần không xóa issue cũ, lọc lại theo HistoryDays ở đây
+	since := time.Now().AddDate(0, 0, -days)
+	fromSpent := j.estimation.Mode == estimateFromSpent
+	recent := []historyIssue{}
+	for _, issue := range issues {
+		if issue.Resolved.After(since) && issue.predicts(fromSpent) {
+			recent = append(recent, issue)
+		}
+	}
+
+	return newHistoryIndex(recent), nil
+}
+
+// syncHistory downloads the resolved issues with an estimate or time spent, so that both
+// modes find them: every HistoryDays on the first
+// run (or after --refresh), then the ones updated since the last sync.
+func (j *Jira) syncHistory(s *store) error {
+	lastSync, err := s.historySync()
+	if err != nil {
+		return err
+	}
+	refresh := defaultHistoryRefresh
+	if j.estimation.HistoryRefresh != "" {
+		if refresh, err = time.ParseDuration(j.estimation.HistoryRefresh); err != nil {
+			return fmt.Errorf("invalid HistoryRefresh %q: %v", j.estimation.HistoryRefresh, err)
+		}
+	}
+	if !lastSync.IsZero() && time.Since(lastSync) < refresh {
+		return nil
+	}
+
+	scope := j.estimation.HistoryJQL
+	if scope == "" {
+		projects, err := myProjects(s)
+		if err != nil {
+			return err
+		}
+		if len(projects) == 0 {
+			fmt.Println(" ⚠️  No project to build the estimation history from, set Estimation.HistoryJQL")
+			return nil
+		}
+		scope = fmt.Sprintf("project IN (%s)", strings.Join(projects, ", "))
+	}
+
+	full := lastSync.IsZero()
+	jql := fmt.Sprintf("(%s) AND resolution IS NOT EMPTY AND (timeoriginalestimate IS NOT EMPTY OR timespent IS NOT EMPTY)", scope)
+	if full {
+		days := j.estimation.HistoryDays
+		if days <= 0 {
+			days = defaultHistoryDays
+		}
+		jql += fmt.Sprintf(" AND resolved >= -%dd", days)
+	} else {
+		jql += fmt.Sprintf(" AND updated >= -%dm", int(time.Since(lastSync).Minutes())+1)
+	}
+
+	started := time.Now()
+	issues, err := j.search(jql, historyFields, historyLimit)
+	if err != nil {
+		return err
+	}
+	history := make([]historyIssue, len(issues))
+	for i := range issues {
+		history[i] = newHistoryIssue(&issues[i])
+	}
+	if err := s.setHistorySync(started, history, full); err != nil {
+		return err
+	}
+
+	fmt.Printf("📚 Indexed %d resolved issues for estimation\n", len(history))
+	return nil
+}
+
+// myProjects returns the keys of the projects of the user's cached issues.
+func myProjects(s *store) ([]string, error) {
+	issues, err := s.myIssues()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	projects := []string{}
+	for _, issue := range issues {
+		if issue.Project != "" && !seen[issue.Project] {
+			seen[issue.Project] = true
+			projects = append(projects, issue.Project)
+		}
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
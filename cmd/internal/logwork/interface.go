@@ -1,6 +1,8 @@
 package logwork
 
 import (
+	"time"
+
 	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
 )
 
@@ -12,3 +14,9 @@ type ProjectTracking interface {
 	FillEstimate(ticket []types.Ticket) error
 	AddEstForTicket(tickets []types.Ticket) error
 }
+
+// EstimatorEvaluator is implemented by the trackers whose estimate suggestions can be
+// replayed against their resolved issues (est eval).
+type EstimatorEvaluator interface {
+	EvaluateEstimator(project string, since time.Time) error
+}
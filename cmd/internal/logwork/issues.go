@@ -0,0 +1,132 @@
+package logwork
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// issueBatchSize is how many keys go in one `key in (...)` search
+const issueBatchSize = 100
+
+var issueStateFields = []string{"status", "timespent", "timeoriginalestimate", "timeestimate"}
+
+// issueStateExpand reads the transitions with the issue, with their fields for the rules
+// that fill some, so that no transition needs a request of its own.
+const issueStateExpand = "transitions.fields"
+
+// issueState is what the submit phase needs to know about an issue.
+type issueState struct {
+	// id is the numeric issue id, Tempo does not take keys
+	id          string
+	status      string
+	categoryKey string
+	category    string
+	spent       int64
+	estimate    int64
+	remaining   int64
+	// transitions are the ones available from status, read with the issue
+	transitions []jira.Transition
+}
+
+func newIssueState(issue *jira.Issue) *issueState {
+	state := &issueState{id: issue.ID, transitions: issue.Transitions}
+	if issue.Fields == nil {
+		return state
+	}
+	state.spent = int64(issue.Fields.TimeSpent)
+	state.estimate = int64(issue.Fields.TimeOriginalEstimate)
+	state.remaining = int64(issue.Fields.TimeEstimate)
+	if issue.Fields.Status != nil {
+		state.status = issue.Fields.Status.Name
+		state.categoryKey = issue.Fields.Status.StatusCategory.Key
+		state.category = issue.Fields.Status.StatusCategory.Name
+	}
+	return state
+}
+
+// issueCache keeps the state of the issues of one LogWork run: fetched in bulk up front, kept
+// up to date locally as worklogs are written and only re-read after a transition.
+type issueCache struct {
+	client *jira.Client
+	states map[string]*issueState
+	// missing are the keys Jira could not read, with the reason
+	missing map[string]error
+}
+
+// searchFunc runs a JQL query, see Jira.searchExpand.
+type searchFunc func(jql string, fields []string, expand string, limit int) ([]jira.Issue, error)
+
+// newIssueCache loads the state and transitions of the given issues with one search per
+// batch of keys. Jira rejects the whole search when one key does not exist (e.g. "UTF-8"
+// picked up from a time entry), such a batch is read key by key instead and the unreadable
+// keys end up in missing.
+func newIssueCache(client *jira.Client, search searchFunc, keys []string) (*issueCache, error) {
+	c := &issueCache{client: client, states: map[string]*issueState{}, missing: map[string]error{}}
+
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	for start := 0; start < len(unique); start += issueBatchSize {
+		batch := unique[start:min(start+issueBatchSize, len(unique))]
+		jql := fmt.Sprintf("key in (%s)", strings.Join(batch, ","))
+		issues, err := search(jql, issueStateFields, issueStateExpand, len(batch))
+		if err != nil {
+			// đọc từng key để tìm key không tồn tại, các key khác vẫn được log
+			for _, key := range batch {
+				if _, err := c.refresh(key); err != nil {
+					c.missing[key] = err
+				}
+			}
+			continue
+		}
+		for i := range issues {
+			c.states[issues[i].Key] = newIssueState(&issues[i])
+		}
+	}
+
+	return c, nil
+}
+
+// get returns the state of an issue, reading it when it was not part of the bulk load.
+func (c *issueCache) get(key string) (*issueState, error) {
+	if state, ok := c.states[key]; ok {
+		return state, nil
+	}
+	return c.refresh(key)
+}
+
+// refresh re-reads an issue, e.g. after a transition changed its status.
+func (c *issueCache) refresh(key string) (*issueState, error) {
+	issue, _, err := c.client.Issue.Get(key, &jira.GetQueryOptions{Fields: strings.Join(issueStateFields, ","), Expand: issueStateExpand})
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch issue %s details: %v", key, err)
+	}
+	state := newIssueState(issue)
+	c.states[key] = state
+	return state, nil
+}
+
+// transitionsOf returns the transitions available from the current status of an issue. They
+// come with the issue, Jira leaves them out only when the expand is not supported.
+func (c *issueCache) transitionsOf(key string) ([]jira.Transition, error) {
+	state, err := c.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if state.transitions == nil {
+		transitions, _, err := c.client.Issue.GetTransitions(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get transitions for %s: %v", key, err)
+		}
+		state.transitions = transitions
+	}
+	return state.transitions, nil
+}
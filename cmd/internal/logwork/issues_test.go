@@ -0,0 +1,112 @@
+package logwork
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestNewIssueCacheFallsBackToGetOnUnknownKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/api/2/issue/APP-1":
+			w.Write([]byte(`{"id": "10001", "key": "APP-1", "fields": {"timespent": 3600, "timeestimate": 7200, "status": {"name": "Open"}}}`))
+		case "/rest/api/2/issue/APP-2":
+			w.Write([]byte(`{"id": "10002", "key": "APP-2", "fields": {"timespent": 0, "status": {"name": "In Progress"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages": ["Issue does not exist or you do not have permission to see it."]}`))
+		}
+	}))
+	defer server.Close()
+	client, err := jira.NewClient(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	searches := []string{}
+	search := func(jql string, fields []string, expand string, limit int) ([]jira.Issue, error) {
+		searches = append(searches, jql)
+		// Jira trả về 400 cho cả câu JQL khi có một key không tồn tại
+		if strings.Contains(jql, "UTF-8") {
+			return nil, errors.New("400: An issue with key 'UTF-8' does not exist for field 'key'.")
+		}
+		return nil, nil
+	}
+
+	cache, err := newIssueCache(client, search, []string{"APP-1", "UTF-8", "APP-2", "APP-1"})
+	if err != nil {
+		t.Fatalf("newIssueCache: %v", err)
+	}
+	if len(searches) != 1 {
+		t.Errorf("searches = %v, want one batch", searches)
+	}
+	if state := cache.states["APP-1"]; state == nil || state.id != "10001" || state.remaining != 7200 {
+		t.Errorf("APP-1 state = %+v", state)
+	}
+	if state := cache.states["APP-2"]; state == nil || state.status != "In Progress" {
+		t.Errorf("APP-2 state = %+v", state)
+	}
+	if _, ok := cache.missing["UTF-8"]; !ok || len(cache.missing) != 1 {
+		t.Errorf("missing = %v, want UTF-8 only", cache.missing)
+	}
+
+	actions := []types.LogAction{
+		{TicketToLog: types.Ticket{ID: "APP-1"}, TimeToLog: 3600},
+		{TicketToLog: types.Ticket{ID: "UTF-8"}, TimeToLog: 1800},
+		{TicketToLog: types.Ticket{ID: "APP-2"}, TimeToLog: 900},
+	}
+	kept, comments := dropMissingIssues(cache, actions, []string{"one", "utf", "two"})
+	if len(kept) != 2 || kept[0].TicketToLog.ID != "APP-1" || kept[1].TicketToLog.ID != "APP-2" {
+		t.Errorf("kept = %+v", kept)
+	}
+	if strings.Join(comments, ",") != "one,two" {
+		t.Errorf("comments = %v, want the comments of the kept worklogs", comments)
+	}
+}
+
+func TestIssueCacheReadsTransitionsWithTheIssues(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	client, err := jira.NewClient(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expands := []string{}
+	search := func(jql string, fields []string, expand string, limit int) ([]jira.Issue, error) {
+		expands = append(expands, expand)
+		return []jira.Issue{
+			{ID: "10001", Key: "APP-1", Fields: &jira.IssueFields{}, Transitions: []jira.Transition{{ID: "21", Name: "PAUSE"}}},
+			{ID: "10002", Key: "APP-2", Fields: &jira.IssueFields{}, Transitions: []jira.Transition{{ID: "31", Name: "Done"}}},
+		}, nil
+	}
+
+	cache, err := newIssueCache(client, search, []string{"APP-1", "APP-2"})
+	if err != nil {
+		t.Fatalf("newIssueCache: %v", err)
+	}
+	if len(expands) != 1 || !strings.Contains(expands[0], "transitions") {
+		t.Errorf("expands = %v, want the transitions in the bulk search", expands)
+	}
+	// transition đã có trong kết quả search, không cần GET riêng cho từng issue
+	for key, want := range map[string]string{"APP-1": "21", "APP-2": "31"} {
+		transitions, err := cache.transitionsOf(key)
+		if err != nil || len(transitions) != 1 || transitions[0].ID != want {
+			t.Errorf("%s transitions = %+v, %v, want %s", key, transitions, err, want)
+		}
+	}
+	if len(requests) != 0 {
+		t.Errorf("requests = %v, want none", requests)
+	}
+}
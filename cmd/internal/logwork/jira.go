@@ -1,13 +1,12 @@
 package logwork
 
 import (
-	"bufio"
-	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/andygrunwald/go-jira"
 	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
@@ -19,120 +18,161 @@ type Jira struct {
 	userName string
 	apiToken string
 	client   *jira.Client
+	// hintField is the custom field holding allocation hints, empty if not used
+	hintField  string
+	dryRun     bool
+	allocation types.AllocationConfig
+	worklog    types.WorklogConfig
+	estimation types.EstimationConfig
+	// yes accepts the estimate suggestions without review, see reviewSuggestions
+	yes bool
+	// transitions are the workflow rules of a run, nil means the default ones
+	transitions []types.TransitionRule
+	// tempo is set when worklogs must go through Tempo Timesheets
+	tempo *tempo
+	// cloud caches whether the instance is Jira Cloud, nil until serverInfo is read
+	cloud *bool
+	// issues is the issue state of the current LogWork run
+	issues *issueCache
+	// refresh forces a full sync of the local store, offline skips the sync
+	refresh bool
+	offline bool
+	// store is the local cache of issues and worklogs, nil until first used
+	store *store
+	// days are the days of the run, --from / --to or the current week
+	days period
 }
 
-func NewJira(endpoint string, userName string, apiToken string) *Jira {
+func NewJira(config *types.Config) *Jira {
 	tp := jira.BasicAuthTransport{
-		Username: userName,
-		Password: apiToken,
+		Username: config.Username,
+		Password: config.ApiToken,
 	}
 
-	client, err := jira.NewClient(tp.Client(), endpoint)
+	client, err := jira.NewClient(tp.Client(), config.Endpoint)
 
 	if err != nil {
 		log.Fatalf("Error creating JIRA client: %v", err)
 	}
 
-	return &Jira{
-		endpoint: endpoint,
-		userName: userName,
-		apiToken: apiToken,
-		client:   client,
+	j := &Jira{
+		endpoint:    config.Endpoint,
+		userName:    config.Username,
+		apiToken:    config.ApiToken,
+		client:      client,
+		hintField:   config.Allocation.HintField,
+		dryRun:      config.DryRun,
+		allocation:  config.Allocation,
+		worklog:     config.Worklog,
+		estimation:  config.Estimation,
+		yes:         config.Yes,
+		transitions: config.Transitions,
+		refresh:     config.Refresh,
+		offline:     config.Offline,
+		days:        newPeriod(config),
 	}
+
+	if config.Tempo.ApiToken != "" && !config.Offline {
+		self, _, err := client.User.GetSelf()
+		if err != nil {
+			log.Fatalf("Error fetching JIRA user for Tempo: %v", err)
+		}
+		j.tempo = newTempo(config.Tempo, self.AccountID)
+	}
+
+	return j
 }
 
 func (j *Jira) GetTicketToLog() ([]types.Ticket, error) {
-	// JQL query to fetch your tickets. Customize this query as needed.
+	// Tickets are read from the local cache. Customize the filter as needed.
 	fmt.Println("----------------Ticket able to log-------------------")
-	jql := fmt.Sprintf(`assignee = "%s" AND status IN (Open, "In Progress", "PAUSED") AND type != Epic AND type != Bug ORDER BY created DESC`, j.userName)
-
-	ticketList := []types.Ticket{}
-
-	issues, _, err := j.client.Issue.SearchV2JQL(jql, &jira.SearchOptionsV2{
-		MaxResults: 1000, // Adjust the number of results as needed
-		Fields:     []string{"summary", "description", "issuetype", "status", "priority", "project", "timeoriginalestimate", "timespent"},
-	})
+	store, err := j.localStore()
 	if err != nil {
 		log.Fatalf("Error fetching JIRA issues: %v", err)
 	}
+	issues, err := store.myIssues()
+	if err != nil {
+		log.Fatalf("Error reading cached JIRA issues: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
 
 	// Print the fetched issues
 	for _, issue := range issues {
-		fmt.Printf("Issue: %s, Summary %s, Est: %s, Status: %s\n", issue.Key, issue.Fields.Summary, helper.FormatEstimate(int64(issue.Fields.TimeOriginalEstimate)), issue.Fields.Status.Name)
-		ticketList = append(ticketList, types.Ticket{
-			ID:              issue.Key,
-			Summary:         issue.Fields.Summary,
-			Est:             int64(issue.Fields.TimeOriginalEstimate),
-			EstimatedLogged: int64(issue.Fields.TimeSpent),
-		})
+		if !issue.inStatus("Open", "In Progress", "PAUSED") || strings.EqualFold(issue.Type, "Epic") || strings.EqualFold(issue.Type, "Bug") {
+			continue
+		}
+		fmt.Printf("Issue: %s, Summary %s, Est: %s, Status: %s\n", issue.Key, issue.Summary, helper.FormatEstimate(issue.Estimate), issue.Status)
+		ticket := issue.ticket()
+
+		if issue.Hints != "" {
+			hints, err := ParseHints(issue.Key, issue.Hints)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allocation hints in %s of %s: %v", j.hintField, issue.Key, err)
+			}
+			ticket.Hints = hints
+		}
+
+		ticketList = append(ticketList, ticket)
 	}
 	return ticketList, nil
 }
 
 func (j *Jira) GetDayToLog() ([]types.LogWorkStatus, error) {
-	// Calculate the start of the current week (Monday)
-	now := time.Now()
-	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
-	var startOfWeek time.Time
-
-	// Sunday is 0 -> we need to handle this
-	if now.Weekday().String() == "Sunday" {
-		startOfWeek = start.AddDate(0, 0, -6) // Adjust according to your week's start day
-	} else {
-		startOfWeek = start.AddDate(0, 0, -int(now.Weekday())+1) // Adjust according to your week's start day
-	}
+	from, to, logworkList := j.days.days()
 
 	fmt.Println("----------------Your week worklog status-------------------")
-	fmt.Println("Start of week: ", startOfWeek)
+	printPeriod(from, to)
 
-	logworkList := make([]types.LogWorkStatus, 7)
+	store, err := j.localStore()
+	if err != nil {
+		log.Fatalf("Error fetching JIRA issues: %v", err)
+	}
+	issues, err := store.myIssues()
+	if err != nil {
+		log.Fatalf("Error reading cached JIRA issues: %v", err)
+	}
 
-	// Create the correct date
-	for i := range logworkList {
-		if i == 0 {
-			logworkList[i].Date = startOfWeek.AddDate(0, 0, 6)
-		} else {
-			logworkList[i].Date = startOfWeek.AddDate(0, 0, i-1)
+	if j.tempo != nil {
+		keys := map[string]string{}
+		for _, issue := range issues {
+			keys[issue.ID] = issue.Key
+		}
+		fmt.Println("Work logs of the period (Tempo):")
+		if err := j.tempo.fillWeek(from, to, logworkList, keys); err != nil {
+			return nil, fmt.Errorf("error fetching Tempo worklogs: %v", err)
 		}
+		printWeekStatus(logworkList)
+		return logworkList, nil
 	}
 
-	// JQL query to fetch issues assigned to you
-	jql := fmt.Sprintf(`assignee = "%s" ORDER BY created DESC`, j.userName)
-
-	issues, _, err := j.client.Issue.SearchV2JQL(jql, &jira.SearchOptionsV2{
-		MaxResults: 1000, // Adjust the number of results as needed
-		Fields:     []string{"summary", "description", "issuetype", "status", "priority", "project"},
-	})
+	worklogs, err := store.worklogs()
 	if err != nil {
-		log.Fatalf("Error fetching JIRA issues: %v", err)
+		log.Fatalf("Error reading cached worklogs: %v", err)
+	}
+	self, err := store.self()
+	if err != nil {
+		log.Fatalf("Error reading cached JIRA user: %v", err)
 	}
 
-	fmt.Println("Work logs for the current week:")
+	fmt.Println("Work logs of the period:")
 
+	mine := map[string]bool{}
 	for _, issue := range issues {
-		worklogs, _, err := j.client.Issue.GetWorklogs(issue.Key)
-		if err != nil {
-			log.Printf("Error fetching worklogs for issue %s: %v", issue.Key, err)
+		mine[issue.Key] = true
+	}
+	for _, worklog := range worklogs {
+		// issue được giao có thể có worklog của đồng nghiệp
+		if worklog.Author != self {
 			continue
 		}
-
-		for _, worklog := range worklogs.Worklogs {
-			worklogTimeStarted, _ := worklog.Started.MarshalJSON()
-			worklogTime, err := time.Parse("\"2006-01-02T15:04:05.999-0700\"", string(worklogTimeStarted))
-			if err != nil {
-				log.Printf("Error parsing worklog time for issue %s: %v", issue.Key, err)
-				continue
-			}
-
-			if worklogTime.After(startOfWeek) {
-				logworkList[worklogTime.Weekday()].Add(int64(worklog.TimeSpentSeconds))
-			}
+		if day := dayOf(logworkList, worklog.Started); mine[worklog.IssueKey] && day != nil {
+			day.AddWorklog(worklog.Started, worklog.TimeSpent)
+			day.AddTicketTime(worklog.IssueKey, worklog.TimeSpent)
 		}
 	}
 
-	for i := range logworkList {
-		fmt.Printf("%s: Time Spent: %d Hours\n", time.Weekday(i), logworkList[i].TimeSpent/3600)
-	}
+	printWeekStatus(logworkList)
 
 	return logworkList, nil
 }
@@ -142,40 +182,82 @@ func (j *Jira) FillEstimate(ticket []types.Ticket) error {
 }
 
 func (j *Jira) LogWork(ticket []types.Ticket, logworkList []types.LogWorkStatus) error {
-	logActionList, _ := defaultLogWorkAlgorithm(ticket, logworkList)
+	logActionList, err := defaultLogWorkAlgorithm(ticket, logworkList, j.allocation)
+	if err != nil {
+		return err
+	}
+	adjust, err := newAdjustRules(j.worklog.AdjustEstimate)
+	if err != nil {
+		return err
+	}
+	comments, err := renderComments(logActionList, j.worklog.Comment)
+	if err != nil {
+		return err
+	}
+	transitions, err := newTransitionRules(j.transitions)
+	if err != nil {
+		return err
+	}
 
-	fmt.Println("----------------Ticket to log-------------------")
-	for i := range logActionList {
-		fmt.Printf("Ticket ID: %s\tTiket Summary: %s\t\tTime to log: %dh\tDate to log: %s\n", logActionList[i].TicketToLog.ID, logActionList[i].TicketToLog.Summary, logActionList[i].TimeToLog/3600, logActionList[i].DateToLog)
+	if j.offline {
+		// không có mạng: không đọc được transition, chỉ in kế hoạch
+		printLogActions(logActionList)
+		printRemainingEstimates(logActionList, adjust)
+		fmt.Println("📴 Offline, nothing was logged.")
+		return nil
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	keys := []string{}
+	for _, action := range logActionList {
+		keys = append(keys, action.TicketToLog.ID)
+	}
+	if j.issues, err = newIssueCache(j.client, j.searchExpand, keys); err != nil {
+		return err
+	}
+	// bỏ worklog của ticket không đọc được, các ticket khác vẫn được log
+	logActionList, comments = dropMissingIssues(j.issues, logActionList, comments)
+	if len(logActionList) == 0 {
+		fmt.Println("No issue of the plan could be read from Jira, nothing was logged.")
+		return nil
+	}
 
-	fmt.Print("You sure to start logging work? [y/n]: ")
-	status, _ := reader.ReadString('\n')
-	status = status[:len(status)-1]
+	printLogActions(logActionList)
+	printRemainingEstimates(logActionList, adjust)
+	printTransitionPlan(j.issues, logActionList, j.transitions)
 
-	status = strings.ReplaceAll(status, "\r", "")
+	if j.tempo != nil {
+		if err := j.tempo.validate(logActionList); err != nil {
+			return err
+		}
+	}
 
-	if status == "n" {
-		return nil
-	} else if status != "y" {
-		log.Println("Invalid input")
-		return errors.New("Invalid input, valid input are y/n")
+	ok, err := confirmPlan(j.dryRun)
+	if err != nil || !ok {
+		return err
 	}
 
-	for i := range logActionList {
-		worklog := &jira.WorklogRecord{
-			Started:          (*jira.Time)(&logActionList[i].DateToLog),
-			TimeSpentSeconds: int(logActionList[i].TimeToLog),
+	for _, raise := range raisedEstimates(logActionList) {
+		if err := j.raiseEstimate(raise.TicketToLog.ID, raise.TimeToLog); err != nil {
+			log.Fatalf("Failed to raise estimate of %s: %v", raise.TicketToLog.ID, err)
 		}
+		fmt.Printf("🔺 Raised estimate of %s by %s\n", raise.TicketToLog.ID, helper.FormatEstimate(raise.TimeToLog))
+	}
 
-		// Log work to the Jira issue
-		_, response, err := j.client.Issue.AddWorklogRecord(logActionList[i].TicketToLog.ID, worklog)
-		if err != nil {
-			log.Fatalf("Failed to log work: %v", err)
+	run := adjust.newRun()
+	for i := range logActionList {
+		// new / manual chỉ áp dụng một lần cho mỗi ticket
+		adjustment := run.next(logActionList[i].TicketToLog.ID)
+		if j.tempo != nil {
+			// Tempo cần id dạng số của issue, không nhận key
+			if err := j.addTempoWorklog(logActionList[i], adjustment, comments[i]); err != nil {
+				log.Fatalf("Failed to log work: %v", err)
+			}
+		} else {
+			// Log work to the Jira issue
+			if err := j.addWorklog(logActionList[i], adjustment, comments[i]); err != nil {
+				log.Fatalf("Failed to log work: %v", err)
+			}
 		}
-		defer response.Body.Close()
 
 		fmt.Printf("Work logged to issue %s: %s successfully.\n", logActionList[i].TicketToLog.ID, logActionList[i].TicketToLog.Summary)
 
@@ -183,139 +265,314 @@ func (j *Jira) LogWork(ticket []types.Ticket, logworkList []types.LogWorkStatus)
 		// -----------------------------
 		// Check status and transition
 		// -----------------------------
-		issue, _, err := j.client.Issue.Get(action.TicketToLog.ID, nil)
+		state, err := j.issues.get(action.TicketToLog.ID)
 		if err != nil {
-			log.Printf("⚠️  Cannot fetch issue %s details: %v\n", action.TicketToLog.ID, err)
+			log.Printf("⚠️  %v\n", err)
 			continue
 		}
+		// cập nhật tại chỗ thay vì đọc lại issue
+		state.spent += action.TimeToLog
+		state.remaining = adjustment.apply(state.remaining, action.TimeToLog)
+		runTransitions(j.issues, transitions, action.TicketToLog.ID, transitions.worklogEvents(action.TicketToLog.ID, *state))
+	}
 
-		if strings.EqualFold(issue.Fields.Status.Name, "Open") {
-			transitions, _, err := j.client.Issue.GetTransitions(action.TicketToLog.ID)
-			if err != nil {
-				log.Printf("⚠️  Cannot get transitions for %s: %v\n", action.TicketToLog.ID, err)
-				continue
-			}
+	// rule cuối lượt chạy cho mỗi ticket đã log
+	done := map[string]bool{}
+	for _, action := range logActionList {
+		id := action.TicketToLog.ID
+		if done[id] || !transitions.logged[id] {
+			continue
+		}
+		done[id] = true
+		runTransitions(j.issues, transitions, id, []string{transitionOnEndOfRun})
+	}
 
-			var pauseTransitionID string
-			for _, t := range transitions {
-				if strings.EqualFold(t.Name, "PAUSE") {
-					pauseTransitionID = t.ID
-					break
-				}
-			}
+	return nil
+}
 
-			if pauseTransitionID == "" {
-				log.Printf("⚠️  No 'Pause' transition found for issue %s\n", action.TicketToLog.ID)
-				continue
+// dropMissingIssues removes the worklogs, and their comments, of the issues Jira could not read.
+func dropMissingIssues(issues *issueCache, logActionList []types.LogAction, comments []string) ([]types.LogAction, []string) {
+	keptActions, keptComments := []types.LogAction{}, []string{}
+	warned := map[string]bool{}
+	for i, action := range logActionList {
+		id := action.TicketToLog.ID
+		if err, ok := issues.missing[id]; ok {
+			if !warned[id] {
+				warned[id] = true
+				log.Printf("⚠️  %v, its worklogs are skipped\n", err)
 			}
+			continue
+		}
+		keptActions = append(keptActions, action)
+		keptComments = append(keptComments, comments[i])
+	}
+	return keptActions, keptComments
+}
 
-			_, err = j.client.Issue.DoTransition(action.TicketToLog.ID, pauseTransitionID)
-			if err != nil {
-				log.Printf("❌ Failed to move issue %s to Pause: %v\n", action.TicketToLog.ID, err)
-			} else {
-				fmt.Printf("🟡 Issue %s transitioned to 'Pause'\n", action.TicketToLog.ID)
-			}
+func (j *Jira) addTempoWorklog(action types.LogAction, adjust adjustEstimate, comment string) error {
+	state, err := j.issues.get(action.TicketToLog.ID)
+	if err != nil {
+		return err
+	}
+
+	issueID, err := strconv.Atoi(state.id)
+	if err != nil {
+		return fmt.Errorf("unexpected issue id %q for %s", state.id, action.TicketToLog.ID)
+	}
+
+	// Tempo không có adjustEstimate, chỉ nhận remaining estimate mới
+	var remaining *int64
+	if adjust.mode != adjustAuto {
+		r := adjust.apply(state.remaining, action.TimeToLog)
+		remaining = &r
+	}
+
+	return j.tempo.addWorklog(issueID, action, remaining, comment)
+}
+
+// isCloud tells from serverInfo whether the instance is Jira Cloud or Server / Data Center.
+// The answer is kept for the rest of the run.
+func (j *Jira) isCloud() (bool, error) {
+	if j.cloud != nil {
+		return *j.cloud, nil
+	}
+
+	req, err := j.client.NewRequest(http.MethodGet, "rest/api/2/serverInfo", nil)
+	if err != nil {
+		return false, err
+	}
+	var info struct {
+		DeploymentType string `json:"deploymentType"`
+	}
+	if _, err := j.client.Do(req, &info); err != nil {
+		return false, err
+	}
+
+	cloud := strings.EqualFold(info.DeploymentType, "Cloud")
+	j.cloud = &cloud
+	return cloud, nil
+}
+
+// addWorklog writes a native Jira worklog. Cloud takes the comment in ADF through REST v3,
+// Server / Data Center takes plain text through REST v2.
+func (j *Jira) addWorklog(action types.LogAction, adjust adjustEstimate, comment string) error {
+	cloud, err := j.isCloud()
+	if err != nil {
+		return fmt.Errorf("cannot read Jira server info: %v", err)
+	}
+	options := jira.WithQueryOptions(jiraAdjustOptions(adjust))
+
+	if !cloud {
+		worklog := &jira.WorklogRecord{
+			Started:          (*jira.Time)(&action.DateToLog),
+			TimeSpentSeconds: int(action.TimeToLog),
+			Comment:          comment,
 		}
+		_, _, err := j.client.Issue.AddWorklogRecord(action.TicketToLog.ID, worklog, options)
+		return err
 	}
 
-	return nil
+	worklog := map[string]interface{}{
+		"started":          action.DateToLog.Format("2006-01-02T15:04:05.000-0700"),
+		"timeSpentSeconds": action.TimeToLog,
+	}
+	if comment != "" {
+		worklog["comment"] = adfDocument(comment)
+	}
+	req, err := j.client.NewRequest(http.MethodPost, fmt.Sprintf("rest/api/3/issue/%s/worklog", url.PathEscape(action.TicketToLog.ID)), worklog)
+	if err != nil {
+		return err
+	}
+	if err := options(req); err != nil {
+		return err
+	}
+	created := map[string]interface{}{}
+	_, err = j.client.Do(req, &created)
+	return err
 }
 
-// GetTicketToEst fetches tickets assigned to the current user (Open / In Progress / PAUSED),
-// then for any Open ticket with Est == 0 it searches the whole JIRA for similar summaries
-// that have timeoriginalestimate > 0 and uses the best match (score >= 0.8) to fill Est.
-func (j *Jira) GetTicketToEst() ([]types.Ticket, error) {
-	fmt.Println("----------------Ticket need to estimate (searching whole Jira)-------------------")
+// jiraAdjustOptions are the query parameters of a worklog creation moving the remaining
+// estimate as adjust says.
+func jiraAdjustOptions(adjust adjustEstimate) interface{} {
+	options := struct {
+		AdjustEstimate string `url:"adjustEstimate"`
+		NewEstimate    string `url:"newEstimate,omitempty"`
+		ReduceBy       string `url:"reduceBy,omitempty"`
+	}{AdjustEstimate: adjust.mode}
+
+	switch adjust.mode {
+	case adjustNew:
+		options.NewEstimate = helper.SecondsToJiraString(adjust.value)
+	case adjustManual:
+		options.ReduceBy = helper.SecondsToJiraString(adjust.value)
+	}
+	return options
+}
 
-	// 1) Lấy các ticket của user để xử lý (các ticket bạn muốn fill)
-	jqlForUser := fmt.Sprintf(`assignee = "%s" AND type IN (Sub-task, Task) AND status IN (Open, Backlog, Paused, "In Review", "In Build", Reopened) ORDER BY created DESC`, j.userName)
+// raiseEstimate grows the original and remaining estimates of an issue by seconds, so the
+// worklogs about to be written do not run over the estimate.
+func (j *Jira) raiseEstimate(ticketID string, seconds int64) error {
+	state, err := j.issues.get(ticketID)
+	if err != nil {
+		return err
+	}
+
+	update := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"timetracking": map[string]interface{}{
+				"originalEstimate":  helper.SecondsToJiraString(state.estimate + seconds),
+				"remainingEstimate": helper.SecondsToJiraString(state.remaining + seconds),
+			},
+		},
+	}
+	if _, err := j.client.Issue.UpdateIssue(ticketID, update); err != nil {
+		return err
+	}
 
-	issues, _, err := j.client.Issue.SearchV2JQL(jqlForUser, &jira.SearchOptionsV2{
-		MaxResults: 1000,
-		Fields:     []string{"summary", "status", "timeoriginalestimate", "timespent"},
-	})
+	state.estimate += seconds
+	state.remaining += seconds
+	return nil
+}
+
+// ticketsToEst returns the cached tickets of the user the estimators work on.
+func (j *Jira) ticketsToEst() ([]types.Ticket, error) {
+	store, err := j.localStore()
 	if err != nil {
 		return nil, fmt.Errorf("error fetching user issues: %v", err)
 	}
+	issues, err := store.myIssues()
+	if err != nil {
+		return nil, fmt.Errorf("error reading cached user issues: %v", err)
+	}
 
 	ticketList := []types.Ticket{}
 	for _, issue := range issues {
-		ticketList = append(ticketList, types.Ticket{
-			ID:              issue.Key,
-			Summary:         issue.Fields.Summary,
-			Status:          issue.Fields.Status.Name,
-			Est:             int64(issue.Fields.TimeOriginalEstimate),
-			EstimatedLogged: int64(issue.Fields.TimeSpent),
-		})
+		if !strings.EqualFold(issue.Type, "Sub-task") && !strings.EqualFold(issue.Type, "Task") {
+			continue
+		}
+		if issue.inStatus("Open", "Backlog", "Paused", "In Review", "In Build", "Reopened") {
+			ticketList = append(ticketList, issue.ticket())
+		}
+	}
+	return ticketList, nil
+}
+
+// GetTicketToEst fetches tickets assigned to the current user, then for any Open ticket with
+// Est == 0 it takes the first matching Estimation.Rules estimate, or else asks the estimator
+// for similar issues. The suggestion is the estimate of the best match (score >= the estimator
+// threshold), or in "spent" mode predicted from the time spent on the top matches, and Est is
+// filled once it is reviewed. EstimateSource records the rule or the match behind each Est.
+func (j *Jira) GetTicketToEst() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket need to estimate-------------------")
+
+	mode := j.estimation.Mode
+	switch mode {
+	case "":
+		mode = estimateFromEstimate
+	case estimateFromEstimate, estimateFromSpent:
+	default:
+		return nil, fmt.Errorf("invalid estimation mode %q, expected estimate or spent", mode)
+	}
+	topK := j.estimation.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+	rules, err := newEstimateRules(j.estimation.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1) Lấy các ticket của user để xử lý (các ticket bạn muốn fill)
+	ticketList, err := j.ticketsToEst()
+	if err != nil {
+		return nil, err
 	}
 
 	fmt.Printf("Fetched %d tickets assigned to %s\n", len(ticketList), j.userName)
 
-	// 2) Với mỗi ticket cần fill (Open và Est == 0) -> search toàn Jira để tìm candidate có Est > 0
-	fmt.Println("\n----------------Auto-fill estimate by searching-------------------")
+	estimator, err := j.newEstimator(false)
+	if err != nil {
+		return nil, err
+	}
 
+	var ratios *accuracyRatios
+	if mode == estimateFromSpent && j.estimation.PersonalRatio {
+		// tỷ lệ cá nhân luôn tính trên history index, estimator nào cũng vậy
+		index, err := j.historyIndex()
+		if err != nil {
+			return nil, err
+		}
+		store, err := j.localStore()
+		if err != nil {
+			return nil, err
+		}
+		mine, err := store.myIssues()
+		if err != nil {
+			return nil, err
+		}
+		ratios = newAccuracyRatios(index.issues, mine)
+		fmt.Printf("Personal accuracy ratio (spent / estimate): %.2f\n", ratios.mine)
+	}
+
+	// 2) Với mỗi ticket cần fill (Open và Est == 0) -> tìm issue tương tự
+	fmt.Println("\n----------------Estimate suggestions-------------------")
+
+	suggestions := []suggestion{}
 	for idx := range ticketList {
 		t := &ticketList[idx]
-		// Chỉ quan tâm Open + chưa có estimate
-		if t.Est > 0 {
+		// AddEstForTicket chỉ ghi estimate cho ticket Open
+		if !strings.EqualFold(t.Status, "Open") || t.Est > 0 {
 			continue
 		}
 
-		fmt.Printf("Searching matches for: %s (%s)\n", t.ID, t.Summary)
-
-		// Tạo keywords từ summary, ưu tiên từ dài hơn (>= 4 ký tự) để tăng độ đặc trưng
-		keywords := helper.ExtractKeywords(t.Summary, 4)
-		if len(keywords) == 0 {
-			fmt.Printf(" ⚠️  No useful keywords found for %s, skipping\n", t.ID)
+		// rule trong config được ưu tiên trước khi tìm issue tương tự
+		if rule := matchEstimateRule(rules, *t); rule != nil {
+			applyEstimateRule(t, rule)
 			continue
 		}
 
-		// Build JQL: tìm các issue có summary chứa ít nhất một keyword và có estimate
-		jqlSearch := helper.BuildJQLForKeywords(keywords)
-		jqlSearch = fmt.Sprintf("(%s) AND timeoriginalestimate IS NOT EMPTY ORDER BY created DESC", jqlSearch)
+		fmt.Printf("Searching matches for: %s (%s)\n", t.ID, t.Summary)
 
-		candidates, _, err := j.client.Issue.SearchV2JQL(jqlSearch, &jira.SearchOptionsV2{
-			MaxResults: 500,
-			Fields:     []string{"summary", "timeoriginalestimate", "status"},
-		})
+		matches, err := estimator.Similar(*t, max(topK, reviewCandidates))
 		if err != nil {
-			log.Printf(" ⚠️  Error searching Jira for %s: %v\n", t.ID, err)
+			log.Printf(" ⚠️  Error searching matches for %s: %v\n", t.ID, err)
 			continue
 		}
-
-		if len(candidates) == 0 {
-			fmt.Printf(" ❌  No candidates found in Jira for %s\n", t.ID)
+		if len(matches) == 0 {
+			fmt.Printf(" ❌  No candidates found for %s\n", t.ID)
 			continue
 		}
-
-		// Evaluate similarity over candidates and pick best
-		bestScore := 0.0
-		bestEst := int64(0)
-		bestSummary := ""
-		bestID := ""
-		for _, c := range candidates {
-			cSummary := c.Fields.Summary
-			cEst := int64(c.Fields.TimeOriginalEstimate)
-			if cEst <= 0 {
-				continue
+		s := suggestion{ticket: idx, fromSpent: mode == estimateFromSpent, candidates: matches[:min(reviewCandidates, len(matches))]}
+
+		if mode == estimateFromSpent {
+			p, ok := predictFromSpent(matches[:min(topK, len(matches))], estimator.Threshold(), ratios)
+			if ok {
+				s.seconds, s.score = p.seconds, p.neighbours[0].score
+				s.source = fmt.Sprintf("time spent on %d similar issues", len(p.neighbours))
+				fmt.Printf(" 💡 Suggested %s => %s (80%% interval %s - %s, from the time spent on %d similar issues, ratio %.2f)\n",
+					t.ID, helper.FormatEstimate(s.seconds), helper.FormatEstimate(p.low), helper.FormatEstimate(p.high), len(p.neighbours), p.ratio)
+				for _, m := range p.neighbours {
+					fmt.Printf("    %s spent %s (est %s), score=%.2f: %s\n", m.issue.Key, helper.FormatEstimate(m.issue.Spent), helper.FormatEstimate(m.issue.Estimate), m.score, m.issue.Summary)
+				}
+			} else {
+				fmt.Printf(" ❌  No sufficiently similar candidate with time spent for %s (best score %.2f, %s)\n", t.ID, s.candidates[0].score, s.candidates[0].issue.Key)
 			}
-			// Tăng độ chính xác bằng cách sử dụng n-gram hoặc fuzzy matching nếu có
-			score := helper.StringSimilarity(t.Summary, cSummary)
-			if score > bestScore {
-				bestScore = score
-				bestEst = cEst
-				bestSummary = cSummary
-				bestID = c.Key
+		} else {
+			best := s.candidates[0]
+			if best.score >= estimator.Threshold() {
+				s.seconds, s.score = best.issue.Estimate, best.score
+				s.source = "similar to " + best.issue.Key
+				fmt.Printf(" 💡 Suggested %s => %s (matched with \"%s\" (ID: %s, spent %s), score=%.2f, text=%.2f, context=%.2f)\n",
+					t.ID, helper.FormatEstimate(s.seconds), best.issue.Summary, best.issue.Key, helper.FormatEstimate(best.issue.Spent), best.score, best.text, best.context)
+			} else {
+				fmt.Printf(" ❌  No sufficiently similar candidate for %s (best score %.2f, %s)\n", t.ID, best.score, best.issue.Key)
 			}
 		}
+		suggestions = append(suggestions, s)
+	}
 
-		// Tăng threshold lên 0.95 để match chặt chẽ hơn
-		if bestScore >= 0.95 && bestEst > 0 {
-			t.Est = bestEst
-			fmt.Printf(" ✅ Auto-filled %s => %s (matched with \"%s\", score=%.2f, task: %s)\n", t.ID, helper.FormatEstimate(t.Est), bestSummary, bestScore, bestID)
-		} else {
-			fmt.Printf(" ❌  No sufficiently similar candidate for %s (best score %.2f)\n", t.ID, bestScore)
-		}
+	if err := reviewSuggestions(ticketList, suggestions, j.yes, autoAcceptScore(j.estimation)); err != nil {
+		return nil, err
 	}
 
 	return ticketList, nil
@@ -323,6 +580,10 @@ func (j *Jira) GetTicketToEst() ([]types.Ticket, error) {
 
 func (j *Jira) AddEstForTicket(ticketList []types.Ticket) error {
 	fmt.Println("\n----------------Updating estimate to Jira-------------------")
+	if j.offline {
+		fmt.Println("📴 Offline, no estimate was updated.")
+		return nil
+	}
 
 	for _, t := range ticketList {
 		// chỉ update cho task open và có estimate hợp lệ
@@ -356,185 +617,14 @@ func (j *Jira) AddEstForTicket(ticketList []types.Ticket) error {
 			continue
 		}
 
-		fmt.Printf("✅ Updated estimate %s -> %s\n", t.ID, helper.FormatEstimate(t.Est))
-	}
-
-	return nil
-}
-
-func (j *Jira) GetTicketToEstV2() ([]types.Ticket, error) {
-	fmt.Println("----------------Ticket need to estimate (searching whole Jira)-------------------")
-
-	// 1) Lấy các ticket của user để xử lý
-	jqlForUser := fmt.Sprintf(`assignee = "%s" AND type IN (Sub-task, Task) AND status IN (Open, Backlog, Paused, "In Review", "In Build", Reopened) ORDER BY created DESC`, j.userName)
-
-	issues, _, err := j.client.Issue.SearchV2JQL(jqlForUser, &jira.SearchOptionsV2{
-		MaxResults: 1000,
-		Fields:     []string{"summary", "status", "timeoriginalestimate", "timespent", "project", "labels", "parent", "created"},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("error fetching user issues: %v", err)
-	}
-
-	ticketList := []types.Ticket{}
-	for _, issue := range issues {
-		ticketList = append(ticketList, types.Ticket{
-			ID:              issue.Key,
-			Summary:         issue.Fields.Summary,
-			Status:          issue.Fields.Status.Name,
-			Est:             int64(issue.Fields.TimeOriginalEstimate),
-			EstimatedLogged: int64(issue.Fields.TimeSpent),
-			Project:         issue.Fields.Project.Key,
-			Labels:          issue.Fields.Labels,
-			Parent:          issue.Fields.Parent.Key,
-			Created:         issue.Fields.Created,
-		})
-	}
-
-	fmt.Printf("Fetched %d tickets assigned to %s\n", len(ticketList), j.userName)
-
-	// 2) Với mỗi ticket cần fill (Open và Est == 0) -> search toàn Jira
-	fmt.Println("\n----------------Auto-fill estimate by searching-------------------")
-
-	for idx := range ticketList {
-		t := &ticketList[idx]
-		// Chỉ quan tâm Open + chưa có estimate
-		if !strings.EqualFold(t.Status, "Open") || t.Est > 0 {
-			continue
-		}
-
-		fmt.Printf("Searching matches for: %s (%s)\n", t.ID, t.Summary)
-
-		// Tạo keywords từ summary, ưu tiên từ dài hơn (>= 4 ký tự)
-		keywords := helper.ExtractKeywords(t.Summary, 4)
-		if len(keywords) == 0 {
-			fmt.Printf(" ⚠️  No useful keywords found for %s, skipping\n", t.ID)
-			continue
-		}
-
-		// Build JQL: ưu tiên ticket trong cùng project hoặc parent
-		jqlSearch := helper.BuildJQLForKeywords(keywords)
-		jqlSearch = fmt.Sprintf("(%s) AND timeoriginalestimate IS NOT EMPTY AND (project = %s OR parent = %s) ORDER BY created DESC", jqlSearch, t.Project, t.Parent)
-
-		candidates, _, err := j.client.Issue.SearchV2JQL(jqlSearch, &jira.SearchOptionsV2{
-			MaxResults: 500,
-			Fields:     []string{"summary", "timeoriginalestimate", "status", "project", "labels", "parent", "created"},
-		})
-		if err != nil {
-			log.Printf(" ⚠️  Error searching Jira for %s: %v\n", t.ID, err)
-			continue
-		}
+		fmt.Printf("✅ Updated estimate %s -> %s (%s)\n", t.ID, helper.FormatEstimate(t.Est), t.EstimateSource)
 
-		if len(candidates) == 0 {
-			fmt.Printf(" ❌  No candidates found in Jira for %s\n", t.ID)
-			continue
-		}
-
-		// Evaluate similarity over candidates
-		bestScore := 0.0
-		bestEst := int64(0)
-		bestSummary := ""
-		bestCandidate := types.Ticket{}
-		for _, c := range candidates {
-			cEst := int64(c.Fields.TimeOriginalEstimate)
-			if cEst <= 0 {
-				continue
+		if t.EstimateSource != "" {
+			if _, _, err := j.client.Issue.AddComment(t.ID, &jira.Comment{Body: estimateNote(t)}); err != nil {
+				fmt.Printf(" ⚠️  Cannot comment the estimate source on %s: %v\n", t.ID, err)
 			}
-
-			// Tính summary score
-			summaryScore := helper.StringSimilarity(t.Summary, c.Fields.Summary)
-
-			// Tính context score
-			contextScore := calculateContextScore(*t, types.Ticket{
-				Project: c.Fields.Project.Key,
-				Parent:  c.Fields.Parent.Key,
-				Labels:  c.Fields.Labels,
-				Created: c.Fields.Created,
-			})
-
-			// Tổng điểm = 60% summaryScore + 40% contextScore
-			totalScore := 0.6*summaryScore + 0.4*contextScore
-			if totalScore > bestScore {
-				bestScore = totalScore
-				bestEst = cEst
-				bestSummary = c.Fields.Summary
-				bestCandidate = types.Ticket{
-					ID:      c.Key,
-					Summary: c.Fields.Summary,
-					Project: c.Fields.Project.Key,
-					Parent:  c.Fields.Parent.Key,
-					Labels:  c.Fields.Labels,
-					Created: c.Fields.Created,
-				}
-			}
-		}
-
-		// Threshold 0.90 để match chặt chẽ
-		if bestScore >= 0.90 && bestEst > 0 {
-			t.Est = bestEst
-			fmt.Printf(" ✅ Auto-filled %s => %s (matched with \"%s\" (ID: %s, Project: %s, Parent: %s), score=%.2f, context=%.2f)\n",
-				t.ID, helper.FormatEstimate(t.Est), bestSummary, bestCandidate.ID, bestCandidate.Project, bestCandidate.Parent, bestScore, calculateContextScore(*t, bestCandidate))
-		} else {
-			fmt.Printf(" ❌  No sufficiently similar candidate for %s (best score %.2f)\n", t.ID, bestScore)
-		}
-	}
-
-	return ticketList, nil
-}
-
-// calculateContextScore tính điểm ngữ cảnh dựa trên project, parent, labels và thời gian
-func calculateContextScore(t, c types.Ticket) float64 {
-	contextScore := 0.0
-
-	// 1) Project matching (trọng số cao nhất: 0.4)
-	if t.Project == c.Project {
-		contextScore += 0.4
-	}
-
-	// 2) Parent (epic/sub-task) matching (trọng số 0.3)
-	if t.Parent != "" && t.Parent == c.Parent {
-		contextScore += 0.3
-	}
-
-	// 3) Labels matching (trọng số động dựa trên tỷ lệ labels chung, tối đa 0.2)
-	if len(t.Labels) > 0 && len(c.Labels) > 0 {
-		commonLabels := len(intersect(t.Labels, c.Labels))
-		totalLabels := float64(len(t.Labels) + len(c.Labels) - commonLabels)
-		if totalLabels > 0 {
-			labelScore := float64(commonLabels) / totalLabels * 0.2
-			contextScore += labelScore
 		}
 	}
 
-	// // 4) Thời gian: ưu tiên ticket gần đây hơn (trọng số 0.1)
-	// if !t.Created.Time.IsZero() && !c.Created.Time.IsZero() {
-	// 	timeDiff := t.Created.Sub(c.Created).Hours() / 24 // Chênh lệch ngày
-	// 	if timeDiff < 0 {
-	// 		timeDiff = -timeDiff
-	// 	}
-	// 	// Giảm điểm nếu cách quá 180 ngày, tối đa 0.1
-	// 	timeScore := math.Max(0, 0.1*(1-timeDiff/180))
-	// 	contextScore += timeScore
-	// }
-
-	// Chuẩn hóa contextScore về [0, 1]
-	if contextScore > 1.0 {
-		contextScore = 1.0
-	}
-	return contextScore
-}
-
-// intersect trả về các phần tử chung của hai slice string
-func intersect(a, b []string) []string {
-	set := make(map[string]bool)
-	var result []string
-	for _, item := range a {
-		set[item] = true
-	}
-	for _, item := range b {
-		if set[item] {
-			result = append(result, item)
-		}
-	}
-	return result
+	return nil
 }
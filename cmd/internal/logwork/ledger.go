@@ -0,0 +1,106 @@
+package logwork
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/constant"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// ledgerAccount is the tracker account a ledger entry was logged with, the same ticket id
+// may exist on two organizations or be logged by two users.
+type ledgerAccount struct {
+	Backend  string
+	Endpoint string
+	User     string
+}
+
+// ledgerEntry is one worklog recorded locally for a tracker without native worklogs.
+type ledgerEntry struct {
+	ledgerAccount
+	TicketID  string
+	Started   time.Time
+	TimeSpent int64
+}
+
+// ledger is the local per-day worklog history, stored as JSON next to the config file. The
+// file holds the entries of every account, a ledger only reads and adds those of its account.
+type ledger struct {
+	path    string
+	account ledgerAccount
+	Entries []ledgerEntry
+}
+
+func getLedgerFilePath() string {
+	homeDir := os.Getenv("HOME")
+	return homeDir + "/" + constant.LedgerFile
+}
+
+// openLedger loads the ledger file for an account, or returns an empty ledger if it does not
+// exist yet.
+func openLedger(account ledgerAccount) (*ledger, error) {
+	l := &ledger{path: getLedgerFilePath(), account: account}
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&l.Entries); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *ledger) add(ticketID string, started time.Time, timeSpent int64) {
+	l.Entries = append(l.Entries, ledgerEntry{
+		ledgerAccount: l.account,
+		TicketID:      ticketID,
+		Started:       started,
+		TimeSpent:     timeSpent,
+	})
+}
+
+func (l *ledger) save() error {
+	file, err := os.Create(l.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(l.Entries)
+}
+
+// fillWeek adds the account's ledger entries started in [from, to) to logworkList.
+func (l *ledger) fillWeek(from time.Time, to time.Time, logworkList []types.LogWorkStatus) {
+	for _, e := range l.Entries {
+		if e.ledgerAccount != l.account || e.Started.Before(from) || !e.Started.Before(to) {
+			continue
+		}
+		if day := dayOf(logworkList, e.Started); day != nil {
+			day.AddWorklog(e.Started, e.TimeSpent)
+			day.AddTicketTime(e.TicketID, e.TimeSpent)
+		}
+	}
+}
+
+// ticketTotal returns the total time the account recorded for a ticket across all days.
+func (l *ledger) ticketTotal(ticketID string) int64 {
+	total := int64(0)
+	for _, e := range l.Entries {
+		if e.ledgerAccount == l.account && e.TicketID == ticketID {
+			total += e.TimeSpent
+		}
+	}
+	return total
+}
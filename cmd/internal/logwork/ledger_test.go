@@ -0,0 +1,42 @@
+package logwork
+
+import (
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestLedgerKeepsAccountsApart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	monday := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	mine := ledgerAccount{Backend: azureBackend, Endpoint: "https://dev.azure.com/acme/app", User: "me"}
+	other := ledgerAccount{Backend: azureBackend, Endpoint: "https://dev.azure.com/other/app", User: "me"}
+
+	l, err := openLedger(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.add("42", monday, 3600)
+	if err := l.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// work item 42 của organization khác không được tính cho tài khoản này
+	l, err = openLedger(mine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.add("42", monday, 1800)
+	days := []types.LogWorkStatus{{Date: startOfDay(monday)}}
+	l.fillWeek(startOfDay(monday), startOfDay(monday).AddDate(0, 0, 1), days)
+	if days[0].TimeSpent != 1800 || l.ticketTotal("42") != 1800 {
+		t.Errorf("time spent = %d, total = %d, want only the 30m of this account", days[0].TimeSpent, l.ticketTotal("42"))
+	}
+	if err := l.save(); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ = openLedger(other); len(l.Entries) != 2 || l.ticketTotal("42") != 3600 {
+		t.Errorf("entries = %+v, want both accounts kept in the file", l.Entries)
+	}
+}
@@ -0,0 +1,566 @@
+package logwork
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/rest"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	linearBackend         = "linear"
+	linearDefaultEndpoint = "https://api.linear.app/graphql"
+	linearIssueFields     = "id identifier title estimate createdAt updatedAt state { name } team { key issueEstimationType issueEstimationExtended } labels { nodes { name } } parent { identifier }"
+)
+
+// linearEstimateScales are the estimates a team may set for each issueEstimationType, then the
+// values added by issueEstimationExtended. T-shirt sizes are stored as XS=1 ... XXXL=21.
+var linearEstimateScales = map[string][2][]int{
+	"exponential": {{1, 2, 4, 8, 16}, {32, 64}},
+	"fibonacci":   {{1, 2, 3, 5, 8}, {13, 21}},
+	"linear":      {{1, 2, 3, 4, 5}, {6, 7}},
+	"tShirt":      {{1, 2, 3, 5, 8}, {13, 21}},
+}
+
+// linearDefaultPointsToHours is used when the config has no points table.
+var linearDefaultPointsToHours = map[string]float64{"1": 1, "2": 2, "3": 4, "5": 8, "8": 16}
+
+// Linear logs work on Linear issues. Linear has no worklogs, so each worklog is posted as a
+// structured comment on the issue and kept in the local ledger.
+type Linear struct {
+	endpoint      string
+	userName      string
+	apiToken      string
+	pointsToHours map[float64]float64
+	dryRun        bool
+	allocation    types.AllocationConfig
+	worklog       types.WorklogConfig
+	client        *rest.Client
+	estimation    types.EstimationConfig
+	// yes accepts the estimate suggestions without review, see reviewSuggestions
+	yes bool
+	// days are the days of the run, --from / --to or the current week
+	days period
+}
+
+type linearIssue struct {
+	ID         string    `json:"id"`
+	Identifier string    `json:"identifier"`
+	Title      string    `json:"title"`
+	Estimate   *float64  `json:"estimate"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	State      struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Team struct {
+		Key                     string `json:"key"`
+		IssueEstimationType     string `json:"issueEstimationType"`
+		IssueEstimationExtended bool   `json:"issueEstimationExtended"`
+	} `json:"team"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Parent *struct {
+		Identifier string `json:"identifier"`
+	} `json:"parent"`
+}
+
+type linearIssueConnection struct {
+	Nodes    []linearIssue `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+// NewLinear uses the api token as a Linear personal API key, the endpoint may be left empty.
+func NewLinear(config *types.Config) *Linear {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = linearDefaultEndpoint
+	}
+
+	table := config.Linear.PointsToHours
+	if len(table) == 0 {
+		table = linearDefaultPointsToHours
+	}
+	pointsToHours := map[float64]float64{}
+	for points, hours := range table {
+		p, err := strconv.ParseFloat(points, 64)
+		if err != nil {
+			log.Printf("⚠️  Invalid Linear points %q in config, skipping\n", points)
+			continue
+		}
+		pointsToHours[p] = hours
+	}
+
+	return &Linear{
+		endpoint:      endpoint,
+		userName:      config.Username,
+		apiToken:      config.ApiToken,
+		pointsToHours: pointsToHours,
+		dryRun:        config.DryRun,
+		allocation:    config.Allocation,
+		worklog:       config.Worklog,
+		estimation:    config.Estimation,
+		yes:           config.Yes,
+		days:          newPeriod(config),
+		client:        rest.NewClient(endpoint, http.Header{"Authorization": []string{config.ApiToken}}),
+	}
+}
+
+// graphql runs a query and decodes its data into out.
+func (l *Linear) graphql(query string, variables map[string]interface{}, out interface{}) error {
+	var result struct {
+		Data   interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	result.Data = out
+
+	body := map[string]interface{}{"query": query, "variables": variables}
+	if err := l.client.Do(http.MethodPost, "", "", body, &result); err != nil {
+		return err
+	}
+
+	if len(result.Errors) > 0 {
+		messages := []string{}
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// searchIssues pages through issues matching filter, up to limit issues.
+func (l *Linear) searchIssues(filter map[string]interface{}, limit int) ([]linearIssue, error) {
+	query := `query($filter: IssueFilter, $after: String) {
+		issues(filter: $filter, first: 100, after: $after, orderBy: createdAt) {
+			nodes { ` + linearIssueFields + ` }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	issues := []linearIssue{}
+	variables := map[string]interface{}{"filter": filter}
+	for len(issues) < limit {
+		var data struct {
+			Issues linearIssueConnection `json:"issues"`
+		}
+		if err := l.graphql(query, variables, &data); err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, data.Issues.Nodes...)
+		if !data.Issues.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = data.Issues.PageInfo.EndCursor
+	}
+
+	if len(issues) > limit {
+		issues = issues[:limit]
+	}
+	return issues, nil
+}
+
+func (l *Linear) getIssue(id string) (*linearIssue, error) {
+	var data struct {
+		Issue linearIssue `json:"issue"`
+	}
+	query := `query($id: String!) { issue(id: $id) { ` + linearIssueFields + ` } }`
+	if err := l.graphql(query, map[string]interface{}{"id": id}, &data); err != nil {
+		return nil, err
+	}
+	return &data.Issue, nil
+}
+
+// pointsToSeconds converts story points with the configured table, unknown points give 0.
+func (l *Linear) pointsToSeconds(points float64) int64 {
+	return hoursToSeconds(l.pointsToHours[points])
+}
+
+// secondsToPoints returns the story points whose hours are closest to seconds.
+func (l *Linear) secondsToPoints(seconds int64) float64 {
+	points := make([]float64, 0, len(l.pointsToHours))
+	for p := range l.pointsToHours {
+		points = append(points, p)
+	}
+	sort.Float64s(points)
+
+	best := 0.0
+	bestDiff := math.MaxFloat64
+	for _, p := range points {
+		diff := math.Abs(l.pointsToHours[p] - secondsToHours(seconds))
+		if diff < bestDiff {
+			best = p
+			bestDiff = diff
+		}
+	}
+	return best
+}
+
+func (l *Linear) toTicket(i *linearIssue, lg *ledger) types.Ticket {
+	ticket := types.Ticket{
+		ID:      i.Identifier,
+		Summary: i.Title,
+		Status:  i.State.Name,
+		Project: i.Team.Key,
+		Created: jira.Time(i.CreatedAt),
+		Updated: jira.Time(i.UpdatedAt),
+	}
+	if i.Estimate != nil {
+		ticket.Est = l.pointsToSeconds(*i.Estimate)
+	}
+	if lg != nil {
+		ticket.EstimatedLogged = lg.ticketTotal(i.Identifier)
+	}
+	if i.Parent != nil {
+		ticket.Parent = i.Parent.Identifier
+	}
+	for _, label := range i.Labels.Nodes {
+		ticket.Labels = append(ticket.Labels, label.Name)
+	}
+	return ticket
+}
+
+func linearAssignedFilter() map[string]interface{} {
+	return map[string]interface{}{
+		"assignee": map[string]interface{}{"isMe": map[string]interface{}{"eq": true}},
+		"state":    map[string]interface{}{"type": map[string]interface{}{"nin": []string{"completed", "canceled"}}},
+	}
+}
+
+func (l *Linear) GetTicketToLog() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket able to log-------------------")
+
+	lg, err := openLedger(l.ledgerAccount())
+	if err != nil {
+		return nil, fmt.Errorf("error reading worklog ledger: %v", err)
+	}
+
+	issues, err := l.searchIssues(linearAssignedFilter(), 1000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Linear issues: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
+	for i := range issues {
+		ticket := l.toTicket(&issues[i], lg)
+		fmt.Printf("Issue: %s, Summary %s, Est: %s, Status: %s\n", ticket.ID, ticket.Summary, helper.FormatEstimate(ticket.Est), ticket.Status)
+		ticketList = append(ticketList, ticket)
+	}
+	return ticketList, nil
+}
+
+// ledgerAccount keys the ledger entries of this endpoint and user.
+func (l *Linear) ledgerAccount() ledgerAccount {
+	return ledgerAccount{Backend: linearBackend, Endpoint: l.endpoint, User: l.userName}
+}
+
+func (l *Linear) GetDayToLog() ([]types.LogWorkStatus, error) {
+	from, to, logworkList := l.days.days()
+
+	fmt.Println("----------------Your week worklog status-------------------")
+	printPeriod(from, to)
+
+	lg, err := openLedger(l.ledgerAccount())
+	if err != nil {
+		return nil, fmt.Errorf("error reading worklog ledger: %v", err)
+	}
+
+	fmt.Println("Work logs of the period (local ledger):")
+	lg.fillWeek(from, to, logworkList)
+	printWeekStatus(logworkList)
+
+	return logworkList, nil
+}
+
+func (l *Linear) FillEstimate(ticket []types.Ticket) error {
+	return nil
+}
+
+func (l *Linear) LogWork(ticket []types.Ticket, logworkList []types.LogWorkStatus) error {
+	logActionList, err := defaultLogWorkAlgorithm(ticket, logworkList, l.allocation)
+	if err != nil {
+		return err
+	}
+	comments, err := renderComments(logActionList, l.worklog.Comment)
+	if err != nil {
+		return err
+	}
+
+	printLogActions(logActionList)
+
+	ok, err := confirmPlan(l.dryRun)
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, raise := range raisedEstimates(logActionList) {
+		if err := l.raiseEstimate(raise.TicketToLog.ID, raise.TimeToLog); err != nil {
+			log.Fatalf("Failed to raise estimate of %s: %v", raise.TicketToLog.ID, err)
+		}
+		fmt.Printf("🔺 Raised estimate of %s by %s\n", raise.TicketToLog.ID, helper.FormatEstimate(raise.TimeToLog))
+	}
+
+	lg, err := openLedger(l.ledgerAccount())
+	if err != nil {
+		return fmt.Errorf("error reading worklog ledger: %v", err)
+	}
+
+	for i, action := range logActionList {
+		// comment có cấu trúc cố định để có thể đọc lại khi cần, phần template nằm giữa
+		body := fmt.Sprintf("⏱ Time logged: %s\n\n%s\n\n- date: %s\n- seconds: %d\n- source: luoi-logwork",
+			helper.FormatEstimate(action.TimeToLog), comments[i], action.DateToLog.Format(time.RFC3339), action.TimeToLog)
+
+		if err := l.addComment(action.TicketToLog.ID, body); err != nil {
+			log.Fatalf("Failed to log work: %v", err)
+		}
+
+		lg.add(action.TicketToLog.ID, action.DateToLog, action.TimeToLog)
+		// work đã được log, không lưu được ledger thì tuần sau sẽ log trùng
+		if err := lg.save(); err != nil {
+			return fmt.Errorf("work logged to %s but the worklog ledger cannot be saved: %v", action.TicketToLog.ID, err)
+		}
+
+		fmt.Printf("Work logged to issue %s: %s successfully.\n", action.TicketToLog.ID, action.TicketToLog.Summary)
+	}
+
+	return nil
+}
+
+// addComment posts a markdown comment on an issue.
+func (l *Linear) addComment(id string, body string) error {
+	mutation := `mutation($issueId: String!, $body: String!) { commentCreate(input: { issueId: $issueId, body: $body }) { success } }`
+
+	var data struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	if err := l.graphql(mutation, map[string]interface{}{"issueId": id, "body": body}, &data); err != nil {
+		return err
+	}
+	if !data.CommentCreate.Success {
+		return fmt.Errorf("comment on %s was not created", id)
+	}
+	return nil
+}
+
+// GetTicketToEst fetches the user's issues and, for issues without an estimate, searches
+// Linear for issues with a similar title that have one.
+func (l *Linear) GetTicketToEst() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket need to estimate (searching whole Linear)-------------------")
+
+	rules, err := newEstimateRules(l.estimation.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := l.searchIssues(linearAssignedFilter(), 1000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user issues: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
+	for i := range issues {
+		ticketList = append(ticketList, l.toTicket(&issues[i], nil))
+	}
+
+	fmt.Printf("Fetched %d tickets assigned to %s\n", len(ticketList), l.userName)
+
+	fmt.Println("\n----------------Auto-fill estimate by searching-------------------")
+
+	suggestions := []suggestion{}
+	for idx := range ticketList {
+		t := &ticketList[idx]
+		if t.Est > 0 {
+			continue
+		}
+
+		// rule trong config được ưu tiên trước khi tìm theo tiêu đề
+		if rule := matchEstimateRule(rules, *t); rule != nil {
+			applyEstimateRule(t, rule)
+			continue
+		}
+
+		fmt.Printf("Searching matches for: %s (%s)\n", t.ID, t.Summary)
+
+		keywords := helper.ExtractKeywords(t.Summary, 3)
+		if len(keywords) == 0 {
+			fmt.Printf(" ⚠️  No useful keywords found for %s, skipping\n", t.ID)
+			continue
+		}
+
+		clauses := []map[string]interface{}{}
+		for _, kw := range keywords {
+			clauses = append(clauses, map[string]interface{}{"title": map[string]interface{}{"containsIgnoreCase": kw}})
+		}
+		filter := map[string]interface{}{
+			"or":       clauses,
+			"estimate": map[string]interface{}{"gt": 0},
+		}
+
+		candidates, err := l.searchIssues(filter, 500)
+		if err != nil {
+			log.Printf(" ⚠️  Error searching Linear for %s: %v\n", t.ID, err)
+			continue
+		}
+
+		if len(candidates) == 0 {
+			fmt.Printf(" ❌  No candidates found in Linear for %s\n", t.ID)
+			continue
+		}
+
+		candidateTickets := []types.Ticket{}
+		for i := range candidates {
+			candidateTickets = append(candidateTickets, l.toTicket(&candidates[i], nil))
+		}
+		if s, ok := suggestByTitle(ticketList, idx, candidateTickets); ok {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	if err := reviewSuggestions(ticketList, suggestions, l.yes, autoAcceptScore(l.estimation)); err != nil {
+		return nil, err
+	}
+
+	return ticketList, nil
+}
+
+// teamEstimate rounds points to the nearest estimate allowed by the issue's team scale, the
+// larger one on a tie. Without a known scale it rounds to an integer, Linear only takes those.
+func teamEstimate(issue *linearIssue, points float64) int {
+	scale, ok := linearEstimateScales[issue.Team.IssueEstimationType]
+	if !ok {
+		return max(int(math.Round(points)), 1)
+	}
+	allowed := scale[0]
+	if issue.Team.IssueEstimationExtended {
+		allowed = append(append([]int{}, scale[0]...), scale[1]...)
+	}
+
+	best := allowed[0]
+	for _, v := range allowed {
+		if math.Abs(float64(v)-points) <= math.Abs(float64(best)-points) {
+			best = v
+		}
+	}
+	return best
+}
+
+// setEstimate sets the story points of an issue, rounded to its team scale, and returns the
+// estimate written.
+func (l *Linear) setEstimate(issue *linearIssue, points float64) (int, error) {
+	estimate := teamEstimate(issue, points)
+	mutation := `mutation($id: String!, $estimate: Int) { issueUpdate(id: $id, input: { estimate: $estimate }) { success } }`
+
+	var data struct {
+		IssueUpdate struct {
+			Success bool `json:"success"`
+		} `json:"issueUpdate"`
+	}
+	if err := l.graphql(mutation, map[string]interface{}{"id": issue.ID, "estimate": estimate}, &data); err != nil {
+		return 0, err
+	}
+	if !data.IssueUpdate.Success {
+		return 0, fmt.Errorf("issue %s was not updated", issue.Identifier)
+	}
+	return estimate, nil
+}
+
+// raiseEstimate moves an issue to the smallest story points covering its estimate plus
+// seconds, or the largest points of the table.
+func (l *Linear) raiseEstimate(id string, seconds int64) error {
+	issue, err := l.getIssue(id)
+	if err != nil {
+		return err
+	}
+
+	current := 0.0
+	if issue.Estimate != nil {
+		current = *issue.Estimate
+	}
+	needed := secondsToHours(l.pointsToSeconds(current) + seconds)
+
+	points := make([]float64, 0, len(l.pointsToHours))
+	for p := range l.pointsToHours {
+		points = append(points, p)
+	}
+	sort.Float64s(points)
+	if len(points) == 0 {
+		return fmt.Errorf("no story points table")
+	}
+
+	raised := points[len(points)-1]
+	for _, p := range points {
+		if l.pointsToHours[p] >= needed {
+			raised = p
+			break
+		}
+	}
+	if float64(teamEstimate(issue, raised)) <= current {
+		return nil
+	}
+	_, err = l.setEstimate(issue, raised)
+	return err
+}
+
+func (l *Linear) AddEstForTicket(ticketList []types.Ticket) error {
+	fmt.Println("\n----------------Updating estimate to Linear-------------------")
+
+	for _, t := range ticketList {
+		if t.Est <= 0 {
+			continue
+		}
+
+		issue, err := l.getIssue(t.ID)
+		if err != nil {
+			fmt.Printf(" ⚠️  Cannot fetch issue %s: %v\n", t.ID, err)
+			continue
+		}
+
+		if issue.Estimate != nil && *issue.Estimate > 0 {
+			fmt.Printf("⏭️ %s đã có estimate (%v points), bỏ qua\n", t.ID, *issue.Estimate)
+			continue
+		}
+
+		points := l.secondsToPoints(t.Est)
+		if points <= 0 {
+			fmt.Printf("❌Update fail %s (%s): no story points for %s\n", t.ID, t.Summary, helper.FormatEstimate(t.Est))
+			continue
+		}
+
+		written, err := l.setEstimate(issue, points)
+		if err != nil {
+			fmt.Printf("❌Update fail %s (%s): %v\n", t.ID, t.Summary, err)
+			continue
+		}
+
+		fmt.Printf("✅ Updated estimate %s -> %d points (%s)\n", t.ID, written, helper.FormatEstimate(t.Est))
+
+		if t.EstimateSource != "" {
+			if err := l.addComment(t.ID, estimateNote(t)); err != nil {
+				fmt.Printf(" ⚠️  Cannot comment the estimate source on %s: %v\n", t.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package logwork
+
+import "testing"
+
+func TestTeamEstimateRoundsToTheScale(t *testing.T) {
+	issue := func(scale string, extended bool) *linearIssue {
+		i := &linearIssue{}
+		i.Team.IssueEstimationType, i.Team.IssueEstimationExtended = scale, extended
+		return i
+	}
+
+	for _, tc := range []struct {
+		issue  *linearIssue
+		points float64
+		want   int
+	}{
+		// 2.5 không còn bị cắt xuống 2: hòa thì lấy giá trị lớn hơn
+		{issue("linear", false), 2.5, 3},
+		{issue("fibonacci", false), 4, 5},
+		{issue("fibonacci", false), 13, 8},
+		{issue("fibonacci", true), 13, 13},
+		{issue("exponential", false), 5.5, 4},
+		{issue("exponential", false), 6.5, 8},
+		{issue("tShirt", false), 2.4, 2},
+		{issue("", false), 2.5, 3},
+		{issue("notUsed", false), 0.4, 1},
+	} {
+		if got := teamEstimate(tc.issue, tc.points); got != tc.want {
+			t.Errorf("%s (extended %v): %.1f points => %d, want %d", tc.issue.Team.IssueEstimationType, tc.issue.Team.IssueEstimationExtended, tc.points, got, tc.want)
+		}
+	}
+}
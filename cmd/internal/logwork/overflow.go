@@ -0,0 +1,157 @@
+package logwork
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// Over-estimate policies: what fills the rest of a day once every ticket is at its estimate.
+const (
+	overflowGap      = "gap"
+	overflowRecent   = "recent"
+	overflowFallback = "fallback"
+	overflowRaise    = "raise"
+)
+
+// Sources of the worklogs added by an over-estimate policy, shown in the plan.
+const (
+	sourceOverflow = "overflow-recent"
+	sourceFallback = "overflow-fallback"
+	sourceRaise    = "overflow-raise"
+)
+
+// overflow fills the part of a day the estimates could not cover.
+type overflow struct {
+	policy   string
+	fallback string
+}
+
+func newOverflow(config types.AllocationConfig) (overflow, error) {
+	o := overflow{policy: config.Overflow, fallback: config.FallbackTicket}
+
+	switch o.policy {
+	case "":
+		o.policy = overflowGap
+	case overflowGap, overflowRecent, overflowRaise:
+	case overflowFallback:
+		if o.fallback == "" {
+			return o, fmt.Errorf("overflow policy %q needs a fallback ticket", o.policy)
+		}
+	default:
+		return o, fmt.Errorf("invalid overflow policy %q, expected gap, recent, fallback or raise", o.policy)
+	}
+
+	return o, nil
+}
+
+// fill allocates remaining on the day according to the policy. Tickets without estimate
+// only get time through the recent and raise policies.
+func (o overflow) fill(ticket []types.Ticket, day types.LogWorkStatus, remaining int64, dayLogged map[string]int64) []types.LogAction {
+	logActionList := []types.LogAction{}
+
+	switch o.policy {
+	case overflowFallback:
+		fallback := types.Ticket{ID: o.fallback, Summary: "(fallback ticket)"}
+		for _, t := range ticket {
+			if t.ID == o.fallback {
+				fallback = t
+			}
+		}
+		logActionList = append(logActionList, types.LogAction{
+			TimeToLog:   remaining,
+			DateToLog:   day.Date.Add(startShiftHour),
+			TicketToLog: fallback,
+			Source:      sourceFallback,
+		})
+		markLogged(ticket, logActionList[0])
+		dayLogged[o.fallback] += remaining
+		return logActionList
+
+	case overflowRecent, overflowRaise:
+		source := sourceOverflow
+		if o.policy == overflowRaise {
+			source = sourceRaise
+		}
+
+		for _, idx := range byRecentActivity(ticket) {
+			t := &ticket[idx]
+			timeToLog := remaining
+			if t.Hints.MaxPerDay > 0 {
+				timeToLog = min(timeToLog, t.Hints.MaxPerDay-dayLogged[t.ID])
+			}
+			if timeToLog <= 0 {
+				continue
+			}
+
+			if o.policy == overflowRaise {
+				t.Est = max(t.Est, t.EstimatedLogged) + timeToLog
+			}
+			logActionList = append(logActionList, types.LogAction{
+				TimeToLog:   timeToLog,
+				DateToLog:   day.Date.Add(startShiftHour),
+				TicketToLog: *t,
+				Source:      source,
+			})
+			t.EstimatedLogged += timeToLog
+			dayLogged[t.ID] += timeToLog
+			remaining -= timeToLog
+			if remaining <= 0 {
+				return logActionList
+			}
+		}
+	}
+
+	// gap: để trống và báo lại cho người dùng
+	noEstimate := []string{}
+	for _, t := range ticket {
+		if t.Est <= 0 && !t.Hints.Exclude {
+			noEstimate = append(noEstimate, t.ID)
+		}
+	}
+	fmt.Printf(" ⚠️  %s %s: %s left unlogged, every ticket is at its estimate", day.Date.Weekday(), day.Date.Format(time.DateOnly), helper.FormatEstimate(remaining))
+	if len(noEstimate) > 0 {
+		fmt.Printf(" (no estimate: %s)", strings.Join(noEstimate, ", "))
+	}
+	fmt.Println()
+
+	return logActionList
+}
+
+// byRecentActivity returns the indexes of the tickets that may take overflow, most recently
+// updated first. Excluded tickets are left out.
+func byRecentActivity(ticket []types.Ticket) []int {
+	indexes := []int{}
+	for i := range ticket {
+		if !ticket[i].Hints.Exclude {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return time.Time(ticket[indexes[a]].Updated).After(time.Time(ticket[indexes[b]].Updated))
+	})
+	return indexes
+}
+
+// raisedEstimates sums the time logged through the raise policy per ticket, in plan order:
+// how much each estimate has to grow before the worklogs are written.
+func raisedEstimates(logActionList []types.LogAction) []types.LogAction {
+	raised := []types.LogAction{}
+	index := map[string]int{}
+	for _, action := range logActionList {
+		if action.Source != sourceRaise {
+			continue
+		}
+		if i, ok := index[action.TicketToLog.ID]; ok {
+			raised[i].TimeToLog += action.TimeToLog
+			continue
+		}
+		index[action.TicketToLog.ID] = len(raised)
+		raised = append(raised, action)
+	}
+	return raised
+}
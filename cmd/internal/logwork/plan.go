@@ -0,0 +1,87 @@
+package logwork
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// printLogActions prints the planned worklogs before the user confirms them.
+func printLogActions(logActionList []types.LogAction) {
+	fmt.Println("----------------Ticket to log-------------------")
+	for i := range logActionList {
+		fmt.Printf("Ticket ID: %s\tTiket Summary: %s\t\tTime to log: %s\tDate to log: %s", logActionList[i].TicketToLog.ID, logActionList[i].TicketToLog.Summary, helper.FormatEstimate(logActionList[i].TimeToLog), logActionList[i].DateToLog)
+		source := logActionList[i].Source
+		if source == "" {
+			source = "estimate"
+		}
+		fmt.Printf("\tSource: %s\n", source)
+	}
+}
+
+// printRemainingEstimates prints, for each ticket of the plan, the remaining estimate before
+// and after the worklogs are written with the configured adjustment.
+func printRemainingEstimates(logActionList []types.LogAction, adjust adjustRules) {
+	fmt.Println("----------------Remaining estimate-------------------")
+
+	order, before, after := remainingEstimates(logActionList, adjust)
+	for _, id := range order {
+		fmt.Printf("Ticket ID: %s\tRemaining: %s -> %s\tAdjust: %s\n", id, helper.FormatEstimate(before[id]), helper.FormatEstimate(after[id]), adjust.forTicket(id))
+	}
+}
+
+// remainingEstimates returns the tickets of the plan in order with their remaining estimate
+// before and after the run.
+func remainingEstimates(logActionList []types.LogAction, adjust adjustRules) (order []string, before map[string]int64, after map[string]int64) {
+	before, after = map[string]int64{}, map[string]int64{}
+	for _, action := range logActionList {
+		id := action.TicketToLog.ID
+		if _, ok := before[id]; !ok {
+			order = append(order, id)
+			before[id] = action.TicketToLog.Remaining
+			after[id] = action.TicketToLog.Remaining
+		}
+	}
+	for _, raise := range raisedEstimates(logActionList) {
+		after[raise.TicketToLog.ID] += raise.TimeToLog
+	}
+	run := adjust.newRun()
+	for _, action := range logActionList {
+		id := action.TicketToLog.ID
+		after[id] = run.next(id).apply(after[id], action.TimeToLog)
+	}
+	return order, before, after
+}
+
+// confirmPlan asks whether to write the plan, a dry run stops here.
+func confirmPlan(dryRun bool) (bool, error) {
+	if dryRun {
+		fmt.Println("🔎 Dry run, nothing was logged.")
+		return false, nil
+	}
+	return confirm("You sure to start logging work?")
+}
+
+// confirm asks a y/n question on stdin and reports whether the answer was y.
+func confirm(question string) (bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Printf("%s [y/n]: ", question)
+	status, _ := reader.ReadString('\n')
+	status = strings.TrimSpace(status)
+
+	if status == "n" {
+		return false, nil
+	} else if status != "y" {
+		log.Println("Invalid input")
+		return false, errors.New("Invalid input, valid input are y/n")
+	}
+
+	return true, nil
+}
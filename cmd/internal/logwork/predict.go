@@ -0,0 +1,208 @@
+package logwork
+
+import (
+	"math"
+	"sort"
+)
+
+// Estimation modes, see types.EstimationConfig.
+const (
+	estimateFromEstimate = "estimate"
+	estimateFromSpent    = "spent"
+)
+
+const (
+	defaultTopK = 5
+	// neighbourThreshold is the score the other neighbours need once the best match passed
+	// the threshold of the estimator
+	neighbourThreshold = 0.5
+	// minRatioSamples is how many resolved issues an accuracy ratio needs, 1 below that
+	minRatioSamples = 5
+	// predictionRounding rounds predictions, nobody estimates 2h 7m
+	predictionRounding = 15 * 60
+)
+
+// prediction is an estimate predicted from the time spent on similar issues, with the 80%
+// interval of the values it comes from.
+type prediction struct {
+	seconds int64
+	// low and high are the weighted 10th and 90th quantiles of the time spent by the
+	// neighbours kept
+	low  int64
+	high int64
+	// neighbours are the matches used, best first
+	neighbours []historyMatch
+	// ratio is the personal accuracy ratio applied, 1 if none
+	ratio float64
+}
+
+// accuracyRatios are time spent / original estimate per assignee: above 1 the assignee
+// under-estimates, below 1 over-estimates.
+type accuracyRatios struct {
+	byAssignee map[string]float64
+	mine       float64
+}
+
+func newAccuracyRatios(history []historyIssue, mine []storedIssue) *accuracyRatios {
+	type sums struct {
+		spent, estimate int64
+		n               int
+	}
+	byAssignee := map[string]*sums{}
+	for _, issue := range history {
+		if issue.Assignee == "" || issue.Spent <= 0 || issue.Estimate <= 0 {
+			continue
+		}
+		if byAssignee[issue.Assignee] == nil {
+			byAssignee[issue.Assignee] = &sums{}
+		}
+		s := byAssignee[issue.Assignee]
+		s.spent, s.estimate, s.n = s.spent+issue.Spent, s.estimate+issue.Estimate, s.n+1
+	}
+
+	r := &accuracyRatios{byAssignee: map[string]float64{}}
+	for assignee, s := range byAssignee {
+		r.byAssignee[assignee] = accuracyRatio(s.spent, s.estimate, s.n)
+	}
+
+	// issue đã xong của user trong cache
+	spent, estimate, n := int64(0), int64(0), 0
+	for _, issue := range mine {
+		if issue.StatusCategory == "done" && issue.Spent > 0 && issue.Estimate > 0 {
+			spent, estimate, n = spent+issue.Spent, estimate+issue.Estimate, n+1
+		}
+	}
+	r.mine = accuracyRatio(spent, estimate, n)
+
+	return r
+}
+
+func accuracyRatio(spent int64, estimate int64, n int) float64 {
+	if n < minRatioSamples || estimate <= 0 {
+		return 1
+	}
+	// giới hạn để vài ticket bất thường không làm lệch quá nhiều
+	return math.Min(math.Max(float64(spent)/float64(estimate), 0.25), 4)
+}
+
+func (r *accuracyRatios) of(assignee string) float64 {
+	if ratio, ok := r.byAssignee[assignee]; ok {
+		return ratio
+	}
+	return 1
+}
+
+// predictFromSpent predicts the time a ticket takes from the time spent on its most similar
+// issues (matches, best first), weighted by similarity. The time spent of each neighbour is
+// first divided by its assignee's accuracy ratio then multiplied by the user's when ratios is
+// set. It returns false when the best match is below threshold or nobody logged time.
+func predictFromSpent(matches []historyMatch, threshold float64, ratios *accuracyRatios) (prediction, bool) {
+	p := prediction{ratio: 1}
+	if len(matches) == 0 || matches[0].score < threshold {
+		p.neighbours = matches
+		return p, false
+	}
+
+	neighbours, values := []historyMatch{}, []float64{}
+	for _, m := range matches {
+		if m.issue.Spent <= 0 || m.score < neighbourThreshold {
+			continue
+		}
+		value := float64(m.issue.Spent)
+		if ratios != nil {
+			value /= ratios.of(m.issue.Assignee)
+		}
+		neighbours = append(neighbours, m)
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		p.neighbours = matches
+		return p, false
+	}
+
+	// bỏ outlier, trọng số là độ giống
+	kept, weights := []float64{}, []float64{}
+	for _, i := range trimOutliers(values) {
+		kept = append(kept, values[i])
+		weights = append(weights, neighbours[i].score)
+		p.neighbours = append(p.neighbours, neighbours[i])
+	}
+	if ratios != nil {
+		p.ratio = ratios.mine
+	}
+	p.seconds = roundPrediction(weightedQuantile(kept, weights, 0.5) * p.ratio)
+	p.low = roundPrediction(weightedQuantile(kept, weights, 0.1) * p.ratio)
+	p.high = roundPrediction(weightedQuantile(kept, weights, 0.9) * p.ratio)
+
+	return p, true
+}
+
+// trimOutliers returns the indexes of the values inside the Tukey fences (1.5 IQR), on a log
+// scale since durations spread multiplicatively. Fewer than 4 values are all kept.
+func trimOutliers(values []float64) []int {
+	kept := []int{}
+	if len(values) < 4 {
+		for i := range values {
+			kept = append(kept, i)
+		}
+		return kept
+	}
+
+	logs := make([]float64, len(values))
+	for i, v := range values {
+		logs[i] = math.Log(v)
+	}
+	sorted := append([]float64{}, logs...)
+	sort.Float64s(sorted)
+	q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+	low, high := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+
+	for i, l := range logs {
+		if l >= low && l <= high {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+// quantile interpolates the q quantile of sorted values.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// weightedQuantile interpolates the q quantile of weighted values. Each value sits at the
+// middle of its share of the total weight, so the bounds of a small sample are not simply
+// its least and largest value.
+func weightedQuantile(values []float64, weights []float64, q float64) float64 {
+	indexes := make([]int, len(values))
+	total := 0.0
+	for i := range values {
+		indexes[i] = i
+		total += weights[i]
+	}
+	sort.Slice(indexes, func(a, b int) bool { return values[indexes[a]] < values[indexes[b]] })
+
+	cumulated, prevPos, prev := 0.0, 0.0, values[indexes[0]]
+	for n, i := range indexes {
+		pos := (cumulated + weights[i]/2) / total
+		cumulated += weights[i]
+		if q <= pos {
+			if n == 0 {
+				return values[i]
+			}
+			return prev + (q-prevPos)/(pos-prevPos)*(values[i]-prev)
+		}
+		prevPos, prev = pos, values[i]
+	}
+	return prev
+}
+
+func roundPrediction(seconds float64) int64 {
+	rounded := int64(math.Round(seconds/predictionRounding)) * predictionRounding
+	return max(rounded, predictionRounding)
+}
@@ -0,0 +1,43 @@
+package logwork
+
+import "testing"
+
+func TestPredictFromSpentMedianOfNeighbours(t *testing.T) {
+	matches := []historyMatch{
+		{issue: historyIssue{Key: "APP-1", Spent: 2 * 3600}, score: 0.95},
+		{issue: historyIssue{Key: "APP-2", Spent: 3600}, score: 0.9},
+		{issue: historyIssue{Key: "APP-3", Spent: 3 * 3600}, score: 0.85},
+	}
+
+	p, ok := predictFromSpent(matches, 0.8, nil)
+	if !ok {
+		t.Fatal("no prediction")
+	}
+	if p.seconds != 2*3600 {
+		t.Errorf("prediction = %d, want 2h", p.seconds)
+	}
+}
+
+func TestPredictFromSpentInterval(t *testing.T) {
+	matches := []historyMatch{
+		{issue: historyIssue{Key: "APP-1", Spent: 12 * 3600}, score: 0.9},
+		{issue: historyIssue{Key: "APP-2", Spent: 8 * 3600}, score: 0.9},
+		{issue: historyIssue{Key: "APP-3", Spent: 16 * 3600}, score: 0.9},
+		{issue: historyIssue{Key: "APP-4", Spent: 4 * 3600}, score: 0.6},
+		{issue: historyIssue{Key: "APP-5", Spent: 20 * 3600}, score: 0.6},
+	}
+
+	p, ok := predictFromSpent(matches, 0.8, nil)
+	if !ok {
+		t.Fatal("no prediction")
+	}
+	// 2 neighbour ở hai đầu ít giống hơn nên khoảng 80% nằm trong min - max
+	if p.seconds != 12*3600 || p.low != 4*3600+30*60 || p.high != 19*3600+30*60 {
+		t.Errorf("prediction = %d (%d - %d), want 12h in 4h30m - 19h30m", p.seconds, p.low, p.high)
+	}
+
+	p, _ = predictFromSpent(matches, 0.8, &accuracyRatios{mine: 0.5})
+	if p.seconds != 6*3600 || p.low != 2*3600+15*60 || p.high != 9*3600+45*60 {
+		t.Errorf("prediction with ratio 0.5 = %d (%d - %d), want 6h in 2h15m - 9h45m", p.seconds, p.low, p.high)
+	}
+}
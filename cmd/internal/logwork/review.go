@@ -0,0 +1,173 @@
+package logwork
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	// reviewCandidates is how many candidates the review shows per ticket
+	reviewCandidates = 3
+	// defaultAutoAcceptScore is the score --yes needs when AutoAcceptScore is not set
+	defaultAutoAcceptScore = 0.9
+	// titleMatchThreshold is the title similarity a candidate needs to be suggested by the
+	// backends searching by title only
+	titleMatchThreshold = 0.95
+)
+
+// suggestion is the estimate proposed for a ticket and the candidates it was drawn from.
+type suggestion struct {
+	// ticket is the index of the ticket in the list
+	ticket int
+	// seconds is the proposed estimate, 0 when no candidate was similar enough
+	seconds int64
+	score   float64
+	// fromSpent picks the time spent of a candidate instead of its estimate
+	fromSpent  bool
+	candidates []historyMatch
+	// source describes where seconds comes from, see types.Ticket.EstimateSource
+	source string
+}
+
+// candidateValue is the estimate a candidate gives when picked.
+func (s suggestion) candidateValue(m historyMatch) int64 {
+	if s.fromSpent && m.issue.Spent > 0 {
+		return m.issue.Spent
+	}
+	return m.issue.Estimate
+}
+
+// autoAcceptScore returns the score --yes needs to take a suggestion.
+func autoAcceptScore(config types.EstimationConfig) float64 {
+	if config.AutoAcceptScore <= 0 {
+		return defaultAutoAcceptScore
+	}
+	return config.AutoAcceptScore
+}
+
+// suggestByTitle ranks the candidates with an estimate by title similarity with ticket idx
+// and suggests the estimate of the best one when it scores titleMatchThreshold or more. It
+// returns false when no candidate has an estimate.
+func suggestByTitle(ticketList []types.Ticket, idx int, candidates []types.Ticket) (suggestion, bool) {
+	t := ticketList[idx]
+	matches := []historyMatch{}
+	for _, c := range candidates {
+		if c.Est <= 0 {
+			continue
+		}
+		score := helper.StringSimilarity(t.Summary, c.Summary)
+		matches = append(matches, historyMatch{
+			issue: historyIssue{Key: c.ID, Summary: c.Summary, Estimate: c.Est, Spent: c.EstimatedLogged},
+			text:  score,
+			score: score,
+		})
+	}
+	if len(matches) == 0 {
+		fmt.Printf(" ❌  No candidate with an estimate for %s\n", t.ID)
+		return suggestion{}, false
+	}
+	sort.SliceStable(matches, func(a, b int) bool { return matches[a].score > matches[b].score })
+
+	s := suggestion{ticket: idx, candidates: matches[:min(reviewCandidates, len(matches))]}
+	best := s.candidates[0]
+	if best.score >= titleMatchThreshold {
+		s.seconds, s.score = best.issue.Estimate, best.score
+		s.source = "similar to " + best.issue.Key
+		fmt.Printf(" 💡 Suggested %s => %s (matched with \"%s\" (ID: %s), score=%.2f)\n",
+			t.ID, helper.FormatEstimate(s.seconds), best.issue.Summary, best.issue.Key, best.score)
+	} else {
+		fmt.Printf(" ❌  No sufficiently similar candidate for %s (best score %.2f, %s)\n", t.ID, best.score, best.issue.Key)
+	}
+	return s, true
+}
+
+// reviewSuggestions fills the estimates of the tickets from the suggestions. With yes, the
+// suggestions scoring at least autoAccept are taken and the others skipped, otherwise every
+// suggestion is reviewed on stdin: accept, pick a candidate, type a value or skip.
+func reviewSuggestions(ticketList []types.Ticket, suggestions []suggestion, yes bool, autoAccept float64) error {
+	if len(suggestions) == 0 {
+		return nil
+	}
+	fmt.Println("\n----------------Review estimates-------------------")
+
+	if yes {
+		for _, s := range suggestions {
+			t := &ticketList[s.ticket]
+			if s.seconds > 0 && s.score >= autoAccept {
+				t.Est, t.EstimateSource = s.seconds, s.source
+				fmt.Printf(" ✅ %s => %s (score %.2f)\n", t.ID, helper.FormatEstimate(t.Est), s.score)
+			} else {
+				fmt.Printf(" ⏭️  %s skipped, score %.2f below %.2f\n", t.ID, s.score, autoAccept)
+			}
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for _, s := range suggestions {
+		t := &ticketList[s.ticket]
+		fmt.Printf("\n%s %s\n", t.ID, t.Summary)
+		if s.seconds > 0 {
+			fmt.Printf("  Suggested: %s (score %.2f)\n", helper.FormatEstimate(s.seconds), s.score)
+		} else {
+			fmt.Println("  No suggestion")
+		}
+		for i, m := range s.candidates {
+			fmt.Printf("  %d) %s\test %s, spent %s\tscore=%.2f context=%.2f\t%s\n", i+1, m.issue.Key,
+				helper.FormatEstimate(m.issue.Estimate), helper.FormatEstimate(m.issue.Spent), m.score, m.context, m.issue.Summary)
+		}
+
+		seconds, source, err := askEstimate(reader, s)
+		if err != nil {
+			return err
+		}
+		if seconds > 0 {
+			t.Est, t.EstimateSource = seconds, source
+			fmt.Printf(" ✅ %s => %s\n", t.ID, helper.FormatEstimate(t.Est))
+		} else {
+			fmt.Printf(" ⏭️  %s skipped\n", t.ID)
+		}
+	}
+	return nil
+}
+
+// askEstimate asks for the estimate of one suggestion until the answer is valid, 0 is skip.
+// It also returns where the estimate comes from.
+func askEstimate(reader *bufio.Reader, s suggestion) (int64, string, error) {
+	for {
+		if s.seconds > 0 {
+			fmt.Printf("  [a]ccept, [1-%d] pick, a duration (e.g. 1h30m) or [s]kip: ", len(s.candidates))
+		} else {
+			fmt.Printf("  [1-%d] pick, a duration (e.g. 1h30m) or [s]kip: ", len(s.candidates))
+		}
+		answer, err := reader.ReadString('\n')
+		if err == io.EOF && answer == "" {
+			return 0, "", fmt.Errorf("estimate review aborted")
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+
+		switch {
+		case answer == "a" && s.seconds > 0:
+			return s.seconds, s.source, nil
+		case answer == "s":
+			return 0, "", nil
+		}
+		if i, err := strconv.Atoi(answer); err == nil {
+			if i >= 1 && i <= len(s.candidates) {
+				return s.candidateValue(s.candidates[i-1]), "picked " + s.candidates[i-1].issue.Key, nil
+			}
+		} else if d, err := time.ParseDuration(strings.ReplaceAll(answer, " ", "")); err == nil && d > 0 {
+			return int64(d.Seconds()), "typed in review", nil
+		}
+		fmt.Println("  Invalid input")
+	}
+}
@@ -0,0 +1,213 @@
+package logwork
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	remainderLargest = "largest"
+	remainderLast    = "last"
+	remainderDrop    = "drop"
+)
+
+// rounding is the parsed worklog granularity of AllocationConfig.
+type rounding struct {
+	unit      int64
+	minChunk  int64
+	remainder string
+}
+
+func newRounding(config types.AllocationConfig) (rounding, error) {
+	r := rounding{remainder: config.Remainder}
+
+	if config.Rounding != "" {
+		d, err := time.ParseDuration(config.Rounding)
+		if err != nil || d < time.Minute {
+			return r, fmt.Errorf("invalid rounding %q, expected e.g. 15m or 30m", config.Rounding)
+		}
+		r.unit = int64(d.Seconds())
+	}
+	if config.MinChunk != "" {
+		d, err := time.ParseDuration(config.MinChunk)
+		if err != nil || d <= 0 {
+			return r, fmt.Errorf("invalid minimum chunk %q", config.MinChunk)
+		}
+		r.minChunk = int64(d.Seconds())
+	}
+
+	switch r.remainder {
+	case "":
+		r.remainder = remainderLargest
+	case remainderLargest, remainderLast, remainderDrop:
+	default:
+		return r, fmt.Errorf("invalid remainder strategy %q, expected largest, last or drop", r.remainder)
+	}
+
+	return r, nil
+}
+
+// apply rounds the worklogs allocated on one day. Worklogs of the same ticket and source are merged,
+// then the day total is apportioned in whole units (largest fractional part first), the
+// remainder below one unit goes to the worklog picked by the remainder strategy, and
+// worklogs under the minimum chunk are merged into the largest one. A worklog only grows
+// within the capacity left to its ticket (remaining estimate, max-per-day), the overflow
+// worklogs excepted. Unless the remainder is dropped, the day still sums exactly to what
+// was allocated. Ticket estimates logged and dayLogged are corrected for the difference.
+func (r rounding) apply(ticket []types.Ticket, dayActions []types.LogAction, dayLogged map[string]int64) []types.LogAction {
+	if r.unit == 0 && r.minChunk == 0 {
+		return dayActions
+	}
+
+	merged := []types.LogAction{}
+	index := map[string]int{}
+	total := int64(0)
+	for _, action := range dayActions {
+		total += action.TimeToLog
+		key := action.TicketToLog.ID + "/" + action.Source
+		if i, ok := index[key]; ok {
+			merged[i].TimeToLog += action.TimeToLog
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, action)
+	}
+	if len(merged) == 0 {
+		return merged
+	}
+
+	before := make([]int64, len(merged))
+	headroom := make([]int64, len(merged))
+	for i := range merged {
+		before[i] = merged[i].TimeToLog
+		headroom[i] = roundingHeadroom(ticket, merged[i], dayLogged)
+	}
+	// room tells whether worklog i can take extra more than it was allocated
+	room := func(i int, extra int64) bool {
+		return merged[i].TimeToLog+extra-before[i] <= headroom[i]
+	}
+
+	unit := r.unit
+	if unit == 0 {
+		unit = 1
+	}
+
+	// chia phần nguyên theo đơn vị, phần dư đơn vị ưu tiên ticket có phần lẻ lớn nhất
+	order := make([]int, len(merged))
+	given := int64(0)
+	for i := range merged {
+		order[i] = i
+		merged[i].TimeToLog = before[i] / unit * unit
+		given += merged[i].TimeToLog
+	}
+	sort.SliceStable(order, func(a, b int) bool { return before[order[a]]%unit > before[order[b]]%unit })
+	for given+unit <= total {
+		// một vòng không ai nhận thêm được đơn vị nào thì dừng
+		progressed := false
+		for _, i := range order {
+			if given+unit > total {
+				break
+			}
+			if room(i, unit) {
+				merged[i].TimeToLog += unit
+				given += unit
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	if rest := total - given; rest > 0 && r.remainder != remainderDrop {
+		target := -1
+		switch r.remainder {
+		case remainderLargest:
+			for i := range merged {
+				if room(i, rest) && (target < 0 || merged[i].TimeToLog > merged[target].TimeToLog) {
+					target = i
+				}
+			}
+		case remainderLast:
+			for i := len(merged) - 1; i >= 0 && target < 0; i-- {
+				if room(i, rest) {
+					target = i
+				}
+			}
+		}
+		if target >= 0 {
+			merged[target].TimeToLog += rest
+		} else {
+			// không worklog nào nhận hết phần dư: trả lại cho từng worklog trong giới hạn của nó,
+			// luôn đủ vì mỗi worklog chỉ bị làm tròn xuống so với lúc chia
+			for i := range merged {
+				give := min(rest, headroom[i]-(merged[i].TimeToLog-before[i]))
+				if give > 0 {
+					merged[i].TimeToLog += give
+					rest -= give
+				}
+			}
+		}
+	}
+
+	// gộp worklog nhỏ hơn min chunk vào worklog lớn nhất trong ngày
+	for r.minChunk > 0 {
+		smallest := -1
+		for i := range merged {
+			if merged[i].TimeToLog > 0 && merged[i].TimeToLog < r.minChunk &&
+				(smallest < 0 || merged[i].TimeToLog < merged[smallest].TimeToLog) {
+				smallest = i
+			}
+		}
+		if smallest < 0 {
+			break
+		}
+		largest := -1
+		for i := range merged {
+			if i != smallest && merged[i].TimeToLog > 0 && room(i, merged[smallest].TimeToLog) &&
+				(largest < 0 || merged[i].TimeToLog > merged[largest].TimeToLog) {
+				largest = i
+			}
+		}
+		if largest < 0 {
+			// không worklog nào nhận thêm được, giữ nguyên để tổng không đổi và không vượt giới hạn
+			break
+		}
+		merged[largest].TimeToLog += merged[smallest].TimeToLog
+		merged[smallest].TimeToLog = 0
+	}
+
+	rounded := []types.LogAction{}
+	for i := range merged {
+		for t := range ticket {
+			if ticket[t].ID == merged[i].TicketToLog.ID {
+				ticket[t].EstimatedLogged += merged[i].TimeToLog - before[i]
+			}
+		}
+		dayLogged[merged[i].TicketToLog.ID] += merged[i].TimeToLog - before[i]
+		if merged[i].TimeToLog > 0 {
+			rounded = append(rounded, merged[i])
+		}
+	}
+	return rounded
+}
+
+// roundingHeadroom is how much more than allocated a worklog may get from rounding: the
+// capacity left to its ticket for the day, unlimited for the overflow worklogs which are
+// over the estimate by design.
+func roundingHeadroom(ticket []types.Ticket, action types.LogAction, dayLogged map[string]int64) int64 {
+	switch action.Source {
+	case sourceOverflow, sourceFallback, sourceRaise:
+		return math.MaxInt64 / 2
+	}
+	for i := range ticket {
+		if ticket[i].ID == action.TicketToLog.ID {
+			return max(capacity(&ticket[i], dayLogged), 0)
+		}
+	}
+	return math.MaxInt64 / 2
+}
@@ -0,0 +1,67 @@
+package logwork
+
+import (
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestRoundingKeepsTicketCapacity(t *testing.T) {
+	// APP-1 đã được chia hết phần estimate còn lại, APP-2 bị giới hạn 1h30m mỗi ngày
+	ticket := []types.Ticket{
+		{ID: "APP-1", Est: 3600, EstimatedLogged: 3600},
+		{ID: "APP-2", Est: 8 * 3600, EstimatedLogged: 50 * 60, Hints: types.AllocationHints{MaxPerDay: 90 * 60}},
+		{ID: "APP-3", Est: 8 * 3600, EstimatedLogged: 70 * 60},
+	}
+	dayLogged := map[string]int64{"APP-1": 50 * 60, "APP-2": 50 * 60, "APP-3": 70 * 60}
+	dayActions := []types.LogAction{
+		{TicketToLog: ticket[0], TimeToLog: 50 * 60},
+		{TicketToLog: ticket[1], TimeToLog: 50 * 60},
+		{TicketToLog: ticket[2], TimeToLog: 70 * 60},
+	}
+
+	round := rounding{unit: 30 * 60, minChunk: 60 * 60, remainder: remainderLargest}
+	rounded := round.apply(ticket, dayActions, dayLogged)
+
+	got := map[string]int64{}
+	var total int64
+	for _, a := range rounded {
+		got[a.TicketToLog.ID] += a.TimeToLog
+		total += a.TimeToLog
+	}
+	if total != 170*60 {
+		t.Errorf("day total = %d, want %d", total, 170*60)
+	}
+	if got["APP-1"] > 50*60 {
+		t.Errorf("APP-1 = %d, over its remaining estimate", got["APP-1"])
+	}
+	if got["APP-2"] > 90*60 {
+		t.Errorf("APP-2 = %d, over its max per day", got["APP-2"])
+	}
+	for _, tk := range ticket {
+		if dayLogged[tk.ID] != got[tk.ID] {
+			t.Errorf("%s: dayLogged = %d, want %d", tk.ID, dayLogged[tk.ID], got[tk.ID])
+		}
+	}
+}
+
+func TestScheduleSplitsOnWholeUnits(t *testing.T) {
+	date := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	s := shift{start: 8*time.Hour + 15*time.Minute, lunchStart: 12 * time.Hour, lunchEnd: 13 * time.Hour}
+	dayActions := []types.LogAction{
+		{TicketToLog: types.Ticket{ID: "APP-1"}, TimeToLog: 3*3600 + 30*60},
+		{TicketToLog: types.Ticket{ID: "APP-2"}, TimeToLog: 90 * 60},
+	}
+
+	scheduled := s.schedule(types.LogWorkStatus{Date: date}, dayActions, 30*60)
+	for _, a := range scheduled {
+		if a.TimeToLog%(30*60) != 0 {
+			t.Errorf("%s at %s: %d is not a whole unit", a.TicketToLog.ID, a.DateToLog.Format("15:04"), a.TimeToLog)
+		}
+	}
+	// 11:45-12:00 ngắn hơn một đơn vị nên bỏ trống, APP-2 bắt đầu sau giờ nghỉ trưa
+	if last := scheduled[len(scheduled)-1]; last.TicketToLog.ID != "APP-2" || !last.DateToLog.Equal(date.Add(13*time.Hour)) {
+		t.Errorf("APP-2 starts at %s, want 13:00", last.DateToLog.Format("15:04"))
+	}
+}
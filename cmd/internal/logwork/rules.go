@@ -0,0 +1,104 @@
+package logwork
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// estimateRule is a compiled Estimation.Rules entry.
+type estimateRule struct {
+	types.EstimateRule
+	summary *regexp.Regexp
+	seconds int64
+}
+
+func newEstimateRules(config []types.EstimateRule) ([]estimateRule, error) {
+	rules := []estimateRule{}
+	for i, c := range config {
+		rule := estimateRule{EstimateRule: c}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+
+		d, err := time.ParseDuration(strings.ReplaceAll(c.Estimate, " ", ""))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("estimate rule %s: invalid estimate %q, expected a duration like 1h or 2h30m", rule.Name, c.Estimate)
+		}
+		rule.seconds = int64(d.Seconds())
+
+		if c.Summary != "" {
+			if rule.summary, err = regexp.Compile(c.Summary); err != nil {
+				return nil, fmt.Errorf("estimate rule %s: invalid summary regex: %v", rule.Name, err)
+			}
+		}
+		for _, glob := range append(append([]string{c.Type}, c.Labels...), c.Components...) {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("estimate rule %s: invalid glob %q", rule.Name, glob)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r estimateRule) matches(t types.Ticket) bool {
+	if r.summary != nil && !r.summary.MatchString(t.Summary) {
+		return false
+	}
+	if r.Type != "" && !globMatch(r.Type, t.Type) {
+		return false
+	}
+	for _, glob := range r.Labels {
+		if !anyGlobMatch(glob, t.Labels) {
+			return false
+		}
+	}
+	for _, glob := range r.Components {
+		if !anyGlobMatch(glob, t.Components) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchEstimateRule returns the first rule matching the ticket, nil if none.
+func matchEstimateRule(rules []estimateRule, t types.Ticket) *estimateRule {
+	for i := range rules {
+		if rules[i].matches(t) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// applyEstimateRule fills the estimate of t from rule.
+func applyEstimateRule(t *types.Ticket, rule *estimateRule) {
+	t.Est, t.EstimateSource = rule.seconds, "rule "+rule.Name
+	fmt.Printf(" 📏 %s => %s (rule %s): %s\n", t.ID, helper.FormatEstimate(t.Est), rule.Name, t.Summary)
+}
+
+// estimateNote is the comment left on an issue whose estimate est filled, so the source can
+// be read back from the tracker.
+func estimateNote(t types.Ticket) string {
+	return fmt.Sprintf("Estimate %s filled by luoi-logwork est: %s", helper.FormatEstimate(t.Est), t.EstimateSource)
+}
+
+func globMatch(glob string, value string) bool {
+	ok, _ := path.Match(strings.ToLower(glob), strings.ToLower(value))
+	return ok
+}
+
+func anyGlobMatch(glob string, values []string) bool {
+	for _, value := range values {
+		if globMatch(glob, value) {
+			return true
+		}
+	}
+	return false
+}
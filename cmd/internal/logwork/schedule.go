@@ -0,0 +1,123 @@
+package logwork
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// shift is the parsed working day of AllocationConfig: when it starts and the lunch break,
+// as offsets from midnight.
+type shift struct {
+	start      time.Duration
+	lunchStart time.Duration
+	lunchEnd   time.Duration
+}
+
+func newShift(config types.AllocationConfig) (shift, error) {
+	s := shift{start: startShiftHour}
+
+	if config.DayStart != "" {
+		start, err := parseClock(config.DayStart)
+		if err != nil {
+			return s, fmt.Errorf("invalid day start %q, expected HH:MM", config.DayStart)
+		}
+		s.start = start
+	}
+
+	if config.Lunch != "" {
+		from, to, _ := strings.Cut(config.Lunch, "-")
+		lunchStart, err := parseClock(strings.TrimSpace(from))
+		if err != nil {
+			return s, fmt.Errorf("invalid lunch break %q, expected HH:MM-HH:MM", config.Lunch)
+		}
+		lunchEnd, err := parseClock(strings.TrimSpace(to))
+		if err != nil || lunchEnd <= lunchStart {
+			return s, fmt.Errorf("invalid lunch break %q, expected HH:MM-HH:MM", config.Lunch)
+		}
+		s.lunchStart, s.lunchEnd = lunchStart, lunchEnd
+	}
+
+	return s, nil
+}
+
+// parseClock parses HH:MM into an offset from midnight.
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// occupied returns the periods of the day worklogs must not overlap, sorted by start: lunch,
+// busy periods, existing worklogs and fixed worklogs.
+func (s shift) occupied(day types.LogWorkStatus) []types.Interval {
+	occupied := []types.Interval{}
+	if s.lunchEnd > s.lunchStart {
+		occupied = append(occupied, types.Interval{Start: day.Date.Add(s.lunchStart), End: day.Date.Add(s.lunchEnd)})
+	}
+	occupied = append(occupied, day.Busy...)
+	occupied = append(occupied, day.Logged...)
+	for _, f := range day.Fixed {
+		occupied = append(occupied, types.Interval{Start: f.DateToLog, End: f.DateToLog.Add(time.Duration(f.TimeToLog) * time.Second)})
+	}
+
+	sort.Slice(occupied, func(i, j int) bool { return occupied[i].Start.Before(occupied[j].Start) })
+	return occupied
+}
+
+// schedule gives the allocated worklogs of a day consecutive start times from the start of
+// the shift, skipping occupied periods. A worklog running into an occupied period is split
+// around it, in multiples of unit (the rounding, 0 for none): a free slot shorter than a unit
+// is left empty.
+func (s shift) schedule(day types.LogWorkStatus, dayActions []types.LogAction, unit int64) []types.LogAction {
+	step := time.Duration(unit) * time.Second
+	occupied := s.occupied(day)
+	cursor := day.Date.Add(s.start)
+
+	scheduled := []types.LogAction{}
+	for _, action := range dayActions {
+		remaining := time.Duration(action.TimeToLog) * time.Second
+		for remaining > 0 {
+			// nhảy qua các khoảng bận đang chứa cursor
+			next := time.Time{}
+			for _, o := range occupied {
+				if !o.End.After(cursor) {
+					continue
+				}
+				if !o.Start.After(cursor) {
+					cursor = o.End
+					continue
+				}
+				next = o.Start
+				break
+			}
+
+			chunk := remaining
+			if !next.IsZero() && next.Sub(cursor) < chunk {
+				chunk = next.Sub(cursor)
+				if step > 0 {
+					// cắt theo bội số của đơn vị làm tròn
+					chunk = chunk / step * step
+				}
+				if chunk == 0 {
+					cursor = next
+					continue
+				}
+			}
+
+			part := action
+			part.DateToLog = cursor
+			part.TimeToLog = int64(chunk.Seconds())
+			scheduled = append(scheduled, part)
+
+			cursor = cursor.Add(chunk)
+			remaining -= chunk
+		}
+	}
+	return scheduled
+}
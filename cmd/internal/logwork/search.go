@@ -0,0 +1,144 @@
+package logwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// searchPageSize is the page size asked to the search endpoints, Jira may return less.
+const searchPageSize = 100
+
+// search runs a JQL query on the search endpoint of the deployment and returns at most limit
+// issues: /rest/api/3/search/jql paged by nextPageToken on Cloud, /rest/api/2/search paged
+// by startAt on Server / Data Center. On Cloud the ADF of the rich text system fields is turned
+// into plain text, as REST v2 returns it.
+func (j *Jira) search(jql string, fields []string, limit int) ([]jira.Issue, error) {
+	return j.searchExpand(jql, fields, "", limit)
+}
+
+// searchExpand is search with the expand parameter of the endpoints, e.g. "transitions".
+func (j *Jira) searchExpand(jql string, fields []string, expand string, limit int) ([]jira.Issue, error) {
+	cloud, err := j.isCloud()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read Jira server info: %v", err)
+	}
+	if cloud {
+		return j.searchCloud(jql, fields, expand, limit)
+	}
+	return j.searchServer(jql, fields, expand, limit)
+}
+
+func (j *Jira) searchCloud(jql string, fields []string, expand string, limit int) ([]jira.Issue, error) {
+	issues := []jira.Issue{}
+	token := ""
+
+	for len(issues) < limit {
+		query := url.Values{}
+		query.Set("jql", jql)
+		query.Set("fields", strings.Join(fields, ","))
+		query.Set("maxResults", strconv.Itoa(min(searchPageSize, limit-len(issues))))
+		if expand != "" {
+			query.Set("expand", expand)
+		}
+		if token != "" {
+			query.Set("nextPageToken", token)
+		}
+
+		req, err := j.client.NewRequest(http.MethodGet, "rest/api/3/search/jql?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Issues        []json.RawMessage `json:"issues"`
+			NextPageToken string            `json:"nextPageToken"`
+			IsLast        bool              `json:"isLast"`
+		}
+		resp, err := j.client.Do(req, &page)
+		if err != nil && resp != nil && resp.StatusCode >= http.StatusBadRequest {
+			// lấy thông báo lỗi của Jira trong body
+			return nil, jira.NewJiraError(resp, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, raw := range page.Issues {
+			issue, err := decodeCloudIssue(raw)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, issue)
+		}
+		if page.IsLast || page.NextPageToken == "" || len(page.Issues) == 0 {
+			break
+		}
+		token = page.NextPageToken
+	}
+
+	return issues, nil
+}
+
+func (j *Jira) searchServer(jql string, fields []string, expand string, limit int) ([]jira.Issue, error) {
+	issues := []jira.Issue{}
+
+	for len(issues) < limit {
+		page, resp, err := j.client.Issue.Search(jql, &jira.SearchOptions{
+			StartAt:    len(issues),
+			MaxResults: min(searchPageSize, limit-len(issues)),
+			Fields:     fields,
+			Expand:     expand,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, page...)
+		// Server trả về total, dừng khi đã đọc hết hoặc trang rỗng
+		if len(page) == 0 || len(issues) >= resp.Total {
+			break
+		}
+	}
+
+	return issues, nil
+}
+
+// adfFields are the system fields REST v3 returns in ADF, go-jira decodes them as strings.
+var adfFields = []string{"description", "environment"}
+
+// decodeCloudIssue decodes an issue of REST v3 with its ADF fields turned into plain text.
+func decodeCloudIssue(raw json.RawMessage) (jira.Issue, error) {
+	issue := jira.Issue{}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return issue, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(envelope["fields"], &fields); err == nil {
+		for _, name := range adfFields {
+			value, ok := fields[name]
+			if !ok || len(value) == 0 || value[0] != '{' {
+				continue
+			}
+			var doc interface{}
+			if err := json.Unmarshal(value, &doc); err != nil {
+				return issue, err
+			}
+			fields[name], _ = json.Marshal(strings.TrimSpace(adfText(doc)))
+		}
+		envelope["fields"], _ = json.Marshal(fields)
+	}
+
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return issue, err
+	}
+	err = json.Unmarshal(raw, &issue)
+	return issue, err
+}
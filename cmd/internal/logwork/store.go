@@ -0,0 +1,336 @@
+package logwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/constant"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the local store.
+var (
+	issuesBucket   = []byte("issues")
+	worklogsBucket = []byte("worklogs")
+	metaBucket     = []byte("meta")
+)
+
+// storeVersion is bumped when storedIssue or storedWorklog gains a field, an older cache is
+// then downloaded again in full.
+const storeVersion = 3
+
+// Keys of the meta bucket.
+var (
+	// lastSyncKey is when the last issue sync started
+	lastSyncKey = []byte("lastSync")
+	// worklogSinceKey is where the next worklog sync resumes, in ms as the worklog APIs
+	worklogSinceKey = []byte("worklogSince")
+	// versionKey is the storeVersion of the last full sync
+	versionKey = []byte("version")
+	// selfKey is the id of the user the cache is synced for, as in storedWorklog.Author
+	selfKey = []byte("self")
+)
+
+// storedIssue is the local copy of an issue.
+type storedIssue struct {
+	Key            string
+	ID             string
+	Summary        string
+	Description    string
+	Type           string
+	Status         string
+	StatusCategory string
+	Project        string
+	Parent         string
+	Labels         []string
+	Components     []string
+	Created        time.Time
+	Updated        time.Time
+	Estimate       int64
+	Spent          int64
+	Remaining      int64
+	// Hints is the raw text of the allocation hint field
+	Hints string
+	// Mine is false once the issue is no longer assigned to the user, its worklogs are kept
+	Mine bool
+}
+
+func (i storedIssue) ticket() types.Ticket {
+	return types.Ticket{
+		ID:              i.Key,
+		Summary:         i.Summary,
+		Description:     i.Description,
+		Est:             i.Estimate,
+		EstimatedLogged: i.Spent,
+		Remaining:       i.Remaining,
+		Status:          i.Status,
+		Type:            i.Type,
+		Project:         i.Project,
+		Labels:          i.Labels,
+		Components:      i.Components,
+		Parent:          i.Parent,
+		Created:         jira.Time(i.Created),
+		Updated:         jira.Time(i.Updated),
+	}
+}
+
+// inStatus tells whether the issue is in one of the given statuses, case-insensitive as JQL.
+func (i storedIssue) inStatus(statuses ...string) bool {
+	for _, status := range statuses {
+		if strings.EqualFold(i.Status, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// storedWorklog is the local copy of a worklog.
+type storedWorklog struct {
+	ID       string
+	IssueID  string
+	IssueKey string
+	// Author is the account id on Cloud, the user name on Server / Data Center
+	Author    string
+	Started   time.Time
+	TimeSpent int64
+}
+
+// store is the local cache of the user's issues and their worklogs, one bbolt file per
+// Jira instance and user under the XDG cache dir.
+type store struct {
+	db *bolt.DB
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func getStoreFilePath(endpoint string, userName string) (string, error) {
+	// $XDG_CACHE_HOME, mặc định ~/.cache
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	name := unsafeFileChars.ReplaceAllString(host+"-"+userName, "_") + ".db"
+
+	return filepath.Join(dir, constant.CacheDir, name), nil
+}
+
+func openStore(path string) (*store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	// Timeout tránh treo khi một lần chạy khác đang giữ file
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open cache %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{issuesBucket, worklogsBucket, historyBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &store{db: db}, nil
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+// reset drops every cached issue, worklog and the history before a full sync.
+func (s *store) reset() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{issuesBucket, worklogsBucket, historyBucket, metaBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// syncState returns when the last sync started (zero if never) and where the worklog sync
+// resumes.
+func (s *store) syncState() (lastSync time.Time, worklogSince int64, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if v := meta.Get(lastSyncKey); v != nil {
+			if err := lastSync.UnmarshalText(v); err != nil {
+				return err
+			}
+		}
+		if v := meta.Get(worklogSinceKey); v != nil {
+			return json.Unmarshal(v, &worklogSince)
+		}
+		return nil
+	})
+	return lastSync, worklogSince, err
+}
+
+func (s *store) setSyncState(lastSync time.Time, worklogSince int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		v, err := lastSync.MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := meta.Put(lastSyncKey, v); err != nil {
+			return err
+		}
+		v, _ = json.Marshal(worklogSince)
+		if err := meta.Put(worklogSinceKey, v); err != nil {
+			return err
+		}
+		v, _ = json.Marshal(storeVersion)
+		return meta.Put(versionKey, v)
+	})
+}
+
+// self returns the id of the user the cache is synced for, empty before the first sync.
+func (s *store) self() (string, error) {
+	self := ""
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get(selfKey); v != nil {
+			self = string(v)
+		}
+		return nil
+	})
+	return self, err
+}
+
+func (s *store) setSelf(self string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(selfKey, []byte(self))
+	})
+}
+
+// outdated tells whether the cache was synced by an older version, without the newer fields.
+func (s *store) outdated() (bool, error) {
+	version := 1
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get(versionKey); v != nil {
+			return json.Unmarshal(v, &version)
+		}
+		return nil
+	})
+	return version < storeVersion, err
+}
+
+func (s *store) putIssues(issues []storedIssue) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(issuesBucket)
+		for _, issue := range issues {
+			v, err := json.Marshal(issue)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(issue.Key), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *store) putWorklogs(worklogs []storedWorklog) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(worklogsBucket)
+		for _, worklog := range worklogs {
+			v, err := json.Marshal(worklog)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(worklog.ID), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *store) deleteWorklogs(ids []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(worklogsBucket)
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// myIssues returns the cached issues assigned to the user, newest first as the JQL queries
+// used to return them.
+func (s *store) myIssues() ([]storedIssue, error) {
+	issues, err := s.issues()
+	if err != nil {
+		return nil, err
+	}
+
+	mine := []storedIssue{}
+	for _, issue := range issues {
+		if issue.Mine {
+			mine = append(mine, issue)
+		}
+	}
+	sort.SliceStable(mine, func(a, b int) bool { return mine[a].Created.After(mine[b].Created) })
+	return mine, nil
+}
+
+// issues returns every cached issue, ordered by key.
+func (s *store) issues() ([]storedIssue, error) {
+	issues := []storedIssue{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(issuesBucket).ForEach(func(k, v []byte) error {
+			issue := storedIssue{}
+			if err := json.Unmarshal(v, &issue); err != nil {
+				return fmt.Errorf("corrupted cache entry %s: %v", k, err)
+			}
+			issues = append(issues, issue)
+			return nil
+		})
+	})
+	return issues, err
+}
+
+// worklogs returns every cached worklog.
+func (s *store) worklogs() ([]storedWorklog, error) {
+	worklogs := []storedWorklog{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(worklogsBucket).ForEach(func(k, v []byte) error {
+			worklog := storedWorklog{}
+			if err := json.Unmarshal(v, &worklog); err != nil {
+				return fmt.Errorf("corrupted cache entry %s: %v", k, err)
+			}
+			worklogs = append(worklogs, worklog)
+			return nil
+		})
+	})
+	return worklogs, err
+}
@@ -0,0 +1,334 @@
+package logwork
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// worklogListBatchSize is the most ids /worklog/list takes in one call
+const worklogListBatchSize = 1000
+
+// syncFields are the issue fields kept in the local store.
+var syncFields = []string{"summary", "description", "issuetype", "status", "project", "parent", "labels", "components", "created", "updated", "timeoriginalestimate", "timespent", "timeestimate"}
+
+// worklogChanges is one page of /worklog/updated or /worklog/deleted.
+type worklogChanges struct {
+	Values []struct {
+		WorklogID int64 `json:"worklogId"`
+	} `json:"values"`
+	Until      int64 `json:"until"`
+	LastPage   bool  `json:"lastPage"`
+	IsLastPage *bool `json:"isLastPage"`
+}
+
+func (c worklogChanges) last() bool {
+	// Server trả về lastPage, một số bản Cloud trả về isLastPage
+	if c.IsLastPage != nil {
+		return *c.IsLastPage
+	}
+	return c.LastPage
+}
+
+// localStore returns the local store, synced with Jira once per run unless offline. The
+// store stays open for the run, its file lock goes with the process.
+func (j *Jira) localStore() (*store, error) {
+	if j.store != nil {
+		return j.store, nil
+	}
+
+	path, err := getStoreFilePath(j.endpoint, j.userName)
+	if err != nil {
+		return nil, err
+	}
+	s, err := openStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.offline {
+		lastSync, _, err := s.syncState()
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		if lastSync.IsZero() {
+			s.close()
+			return nil, fmt.Errorf("no local cache yet, run once without --offline")
+		}
+		fmt.Printf("📴 Offline, using the cache synced at %s\n", lastSync.Format(time.DateTime))
+	} else if err := j.sync(s); err != nil {
+		s.close()
+		return nil, fmt.Errorf("cannot sync the local cache: %v", err)
+	}
+
+	j.store = s
+	return s, nil
+}
+
+// sync brings the store up to date: a full download on the first run, with --refresh or when
+// the cache predates storeVersion, otherwise only the issues updated since the last sync and
+// the worklogs reported by the worklog updated / deleted APIs.
+func (j *Jira) sync(s *store) error {
+	lastSync, worklogSince, err := s.syncState()
+	if err != nil {
+		return err
+	}
+	outdated, err := s.outdated()
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	full := j.refresh || lastSync.IsZero() || outdated
+
+	mineJQL := fmt.Sprintf(`assignee = "%s"`, j.userName)
+	// issue đã từng giao cho user nhưng nay không còn
+	formerJQL := fmt.Sprintf(`assignee WAS "%s" AND (assignee != "%s" OR assignee IS EMPTY)`, j.userName, j.userName)
+
+	if full {
+		if err := s.reset(); err != nil {
+			return err
+		}
+		// issue của user có cả worklog của người khác, lưu user để lọc theo Author
+		self, _, err := j.client.User.GetSelf()
+		if err != nil {
+			return fmt.Errorf("error fetching JIRA user: %v", err)
+		}
+		if err := s.setSelf(jiraUserID(self)); err != nil {
+			return err
+		}
+	} else {
+		// JQL tương đối theo phút để không phụ thuộc múi giờ của profile Jira
+		since := fmt.Sprintf(" AND updated >= -%dm", int(time.Since(lastSync).Minutes())+1)
+		mineJQL += since
+		formerJQL += since
+	}
+
+	known := map[string]bool{}
+	cached, err := s.issues()
+	if err != nil {
+		return err
+	}
+	for _, issue := range cached {
+		known[issue.Key] = true
+	}
+
+	mine, err := j.syncIssues(s, mineJQL, true)
+	if err != nil {
+		return err
+	}
+	if !full {
+		if _, err := j.syncIssues(s, formerJQL, false); err != nil {
+			return err
+		}
+	}
+
+	// issue mới vào cache: lấy toàn bộ worklog, các issue khác đi qua worklog API
+	worklogs := []storedWorklog{}
+	for _, issue := range mine {
+		if known[issue.Key] {
+			continue
+		}
+		issueWorklogs, _, err := j.client.Issue.GetWorklogs(issue.Key)
+		if err != nil {
+			return fmt.Errorf("error fetching worklogs for issue %s: %v", issue.Key, err)
+		}
+		for _, worklog := range issueWorklogs.Worklogs {
+			worklogs = append(worklogs, newStoredWorklog(worklog, issue.Key))
+		}
+	}
+	if err := s.putWorklogs(worklogs); err != nil {
+		return err
+	}
+
+	nextSince := started.UnixMilli()
+	updated, deleted := 0, 0
+	if !full {
+		if nextSince, updated, deleted, err = j.syncWorklogChanges(s, worklogSince); err != nil {
+			return err
+		}
+	}
+
+	if err := s.setSyncState(started, nextSince); err != nil {
+		return err
+	}
+
+	if full {
+		fmt.Printf("🗄️  Cached %d issues and %d worklogs\n", len(mine), len(worklogs))
+	} else {
+		fmt.Printf("🗄️  Synced %d updated issues, %d new worklogs, %d changed and %d deleted since %s\n", len(mine), len(worklogs), updated, deleted, lastSync.Format(time.DateTime))
+	}
+	return nil
+}
+
+// syncIssues stores the issues of a JQL query and returns them.
+func (j *Jira) syncIssues(s *store, jql string, mine bool) ([]storedIssue, error) {
+	fields := syncFields
+	if j.hintField != "" {
+		fields = append(append([]string{}, syncFields...), j.hintField)
+	}
+
+	issues, err := j.search(jql, fields, 10000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching JIRA issues: %v", err)
+	}
+
+	stored := []storedIssue{}
+	for i := range issues {
+		issue := newStoredIssue(&issues[i], j.hintField)
+		issue.Mine = mine
+		stored = append(stored, issue)
+	}
+	return stored, s.putIssues(stored)
+}
+
+// syncWorklogChanges applies the worklogs updated and deleted since the given time (ms) to
+// the cached issues. It returns where the next sync resumes.
+func (j *Jira) syncWorklogChanges(s *store, since int64) (int64, int, int, error) {
+	issues, err := s.issues()
+	if err != nil {
+		return since, 0, 0, err
+	}
+	keys := map[string]string{}
+	for _, issue := range issues {
+		keys[issue.ID] = issue.Key
+	}
+
+	updatedIDs, until, err := j.worklogChanges("rest/api/2/worklog/updated", since)
+	if err != nil {
+		return since, 0, 0, err
+	}
+	deletedIDs, deletedUntil, err := j.worklogChanges("rest/api/2/worklog/deleted", since)
+	if err != nil {
+		return since, 0, 0, err
+	}
+
+	worklogs := []storedWorklog{}
+	for start := 0; start < len(updatedIDs); start += worklogListBatchSize {
+		batch := updatedIDs[start:min(start+worklogListBatchSize, len(updatedIDs))]
+		req, err := j.client.NewRequest(http.MethodPost, "rest/api/2/worklog/list", map[string]interface{}{"ids": batch})
+		if err != nil {
+			return since, 0, 0, err
+		}
+		records := []jira.WorklogRecord{}
+		if _, err := j.client.Do(req, &records); err != nil {
+			return since, 0, 0, fmt.Errorf("error fetching updated worklogs: %v", err)
+		}
+		for _, record := range records {
+			// API trả về worklog của mọi issue, chỉ giữ issue có trong cache
+			if key, ok := keys[record.IssueID]; ok {
+				worklogs = append(worklogs, newStoredWorklog(record, key))
+			}
+		}
+	}
+	if err := s.putWorklogs(worklogs); err != nil {
+		return since, 0, 0, err
+	}
+
+	deleted := []string{}
+	for _, id := range deletedIDs {
+		deleted = append(deleted, strconv.FormatInt(id, 10))
+	}
+	if err := s.deleteWorklogs(deleted); err != nil {
+		return since, 0, 0, err
+	}
+
+	// tiếp tục từ chỗ cả hai API đều đã đọc tới, API không có thay đổi thì không giới hạn
+	next := since
+	switch {
+	case len(updatedIDs) > 0 && len(deletedIDs) > 0:
+		next = min(until, deletedUntil)
+	case len(updatedIDs) > 0:
+		next = until
+	case len(deletedIDs) > 0:
+		next = deletedUntil
+	}
+	return next, len(worklogs), len(deleted), nil
+}
+
+// worklogChanges pages through /worklog/updated or /worklog/deleted from since (ms) and
+// returns the worklog ids and the end of the last page.
+func (j *Jira) worklogChanges(path string, since int64) ([]int64, int64, error) {
+	ids := []int64{}
+	for {
+		req, err := j.client.NewRequest(http.MethodGet, path+"?since="+url.QueryEscape(strconv.FormatInt(since, 10)), nil)
+		if err != nil {
+			return nil, since, err
+		}
+		page := worklogChanges{}
+		if _, err := j.client.Do(req, &page); err != nil {
+			return nil, since, fmt.Errorf("error fetching %s: %v", path, err)
+		}
+		for _, v := range page.Values {
+			ids = append(ids, v.WorklogID)
+		}
+		if page.Until > since {
+			since = page.Until
+		}
+		if page.last() || len(page.Values) == 0 {
+			return ids, since, nil
+		}
+	}
+}
+
+func newStoredIssue(issue *jira.Issue, hintField string) storedIssue {
+	stored := storedIssue{Key: issue.Key, ID: issue.ID}
+	f := issue.Fields
+	if f == nil {
+		return stored
+	}
+
+	stored.Summary = f.Summary
+	stored.Description = f.Description
+	stored.Labels = f.Labels
+	stored.Created = time.Time(f.Created)
+	stored.Updated = time.Time(f.Updated)
+	stored.Estimate = int64(f.TimeOriginalEstimate)
+	stored.Spent = int64(f.TimeSpent)
+	stored.Remaining = int64(f.TimeEstimate)
+	stored.Type = f.Type.Name
+	stored.Project = f.Project.Key
+	if f.Status != nil {
+		stored.Status = f.Status.Name
+		stored.StatusCategory = f.Status.StatusCategory.Key
+	}
+	if f.Parent != nil {
+		stored.Parent = f.Parent.Key
+	}
+	for _, component := range f.Components {
+		stored.Components = append(stored.Components, component.Name)
+	}
+	if hintField != "" {
+		stored.Hints = adfText(f.Unknowns[hintField])
+	}
+	return stored
+}
+
+func newStoredWorklog(record jira.WorklogRecord, issueKey string) storedWorklog {
+	worklog := storedWorklog{
+		ID:        record.ID,
+		IssueID:   record.IssueID,
+		IssueKey:  issueKey,
+		Author:    jiraUserID(record.Author),
+		TimeSpent: int64(record.TimeSpentSeconds),
+	}
+	if record.Started != nil {
+		worklog.Started = time.Time(*record.Started)
+	}
+	return worklog
+}
+
+// jiraUserID returns the account id of a user on Cloud, the user name on Server / Data Center.
+func jiraUserID(user *jira.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.AccountID != "" {
+		return user.AccountID
+	}
+	return user.Name
+}
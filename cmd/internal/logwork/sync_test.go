@@ -0,0 +1,39 @@
+package logwork
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJiraGetDayToLogOnlyCountsOwnWorklogs(t *testing.T) {
+	s, err := openStore(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	if err := s.putIssues([]storedIssue{{Key: "APP-1", ID: "10001", Mine: true}}); err != nil {
+		t.Fatal(err)
+	}
+	// đồng nghiệp cũng log trên APP-1
+	if err := s.putWorklogs([]storedWorklog{
+		{ID: "1", IssueKey: "APP-1", Author: "me", Started: monday.Add(9 * time.Hour), TimeSpent: 3600},
+		{ID: "2", IssueKey: "APP-1", Author: "colleague", Started: monday.Add(10 * time.Hour), TimeSpent: 2 * 3600},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.setSelf("me"); err != nil {
+		t.Fatal(err)
+	}
+
+	j := &Jira{store: s, days: period{from: monday, to: monday.AddDate(0, 0, 1)}}
+	days, err := j.GetDayToLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days[0].TimeSpent != 3600 || days[0].Tickets["APP-1"] != 3600 {
+		t.Errorf("time spent = %d, tickets = %v, want only the user's 1h", days[0].TimeSpent, days[0].Tickets)
+	}
+}
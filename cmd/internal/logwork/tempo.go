@@ -0,0 +1,223 @@
+package logwork
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/rest"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	tempoDefaultEndpoint = "https://api.tempo.io/4"
+	tempoAccountKey      = "_Account_"
+)
+
+// tempoLockedStatuses are the approval statuses of a period that no longer takes worklogs,
+// an open period or one ready to submit can still be logged.
+var tempoLockedStatuses = map[string]bool{
+	"WAITING_FOR_APPROVAL": true,
+	"APPROVED":             true,
+}
+
+// tempo reads and writes Jira worklogs through the Tempo Timesheets API, so that work
+// attributes are set and approval periods are respected.
+type tempo struct {
+	config    types.TempoConfig
+	accountID string
+	client    *rest.Client
+	// approval status per approval period, keyed by period start date
+	periods map[string]tempoApproval
+}
+
+type tempoWorklog struct {
+	Issue struct {
+		ID int `json:"id"`
+	} `json:"issue"`
+	TimeSpentSeconds int64  `json:"timeSpentSeconds"`
+	StartDate        string `json:"startDate"`
+	StartTime        string `json:"startTime"`
+}
+
+type tempoApproval struct {
+	Period struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"period"`
+	Status struct {
+		Key string `json:"key"`
+	} `json:"status"`
+}
+
+type tempoAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func newTempo(config types.TempoConfig, accountID string) *tempo {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = tempoDefaultEndpoint
+	}
+
+	return &tempo{
+		config:    config,
+		accountID: accountID,
+		client:    rest.NewClient(endpoint, http.Header{"Authorization": []string{"Bearer " + config.ApiToken}}),
+		periods:   map[string]tempoApproval{},
+	}
+}
+
+// ticketLookup returns the entry of a config map for the ticket key, then project key, then "*".
+func ticketLookup[V any](m map[string]V, ticketID string) (V, bool) {
+	project, _, _ := strings.Cut(ticketID, "-")
+	for _, key := range []string{ticketID, project, "*"} {
+		if v, ok := m[key]; ok {
+			return v, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// attributes builds the Tempo work attributes (account included) for a ticket.
+func (t *tempo) attributes(ticketID string) []tempoAttribute {
+	values := map[string]string{}
+	if attrs, ok := ticketLookup(t.config.Attributes, ticketID); ok {
+		for k, v := range attrs {
+			values[k] = v
+		}
+	}
+	if account, ok := ticketLookup(t.config.Accounts, ticketID); ok {
+		values[tempoAccountKey] = account
+	}
+
+	attributes := []tempoAttribute{}
+	for k, v := range values {
+		attributes = append(attributes, tempoAttribute{Key: k, Value: v})
+	}
+	slices.SortFunc(attributes, func(a, b tempoAttribute) int { return strings.Compare(a.Key, b.Key) })
+	return attributes
+}
+
+func (t *tempo) billable(ticketID string) bool {
+	project, _, _ := strings.Cut(ticketID, "-")
+	return !slices.Contains(t.config.NonBillable, ticketID) &&
+		!slices.Contains(t.config.NonBillable, project) &&
+		!slices.Contains(t.config.NonBillable, "*")
+}
+
+// approval returns the approval status of the period containing date.
+func (t *tempo) approval(date time.Time) (tempoApproval, error) {
+	day := date.Format(time.DateOnly)
+	for _, a := range t.periods {
+		if a.Period.From <= day && day <= a.Period.To {
+			return a, nil
+		}
+	}
+
+	a := tempoApproval{}
+	path := fmt.Sprintf("/timesheet-approvals/user/%s?from=%s&to=%s", url.PathEscape(t.accountID), day, day)
+	if err := t.client.Do(http.MethodGet, path, "", nil, &a); err != nil {
+		return a, err
+	}
+	if a.Period.From == "" {
+		a.Period.From, a.Period.To = day, day
+	}
+	t.periods[a.Period.From] = a
+	return a, nil
+}
+
+// validate checks every planned worklog against account mapping and locked approval
+// periods before anything is written.
+func (t *tempo) validate(logActionList []types.LogAction) error {
+	problems := []string{}
+	for _, action := range logActionList {
+		if _, ok := ticketLookup(t.config.Accounts, action.TicketToLog.ID); t.config.RequireAccount && !ok {
+			problems = append(problems, fmt.Sprintf("%s has no Tempo account mapped", action.TicketToLog.ID))
+		}
+
+		a, err := t.approval(action.DateToLog)
+		if err != nil {
+			return fmt.Errorf("error fetching Tempo approval period: %v", err)
+		}
+		if tempoLockedStatuses[a.Status.Key] {
+			problems = append(problems, fmt.Sprintf("%s on %s: period %s..%s is %s",
+				action.TicketToLog.ID, action.DateToLog.Format(time.DateOnly), a.Period.From, a.Period.To, a.Status.Key))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cannot log work through Tempo:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// fillWeek adds the user's Tempo worklogs of [from, to) to logworkList. Tempo only gives the
+// numeric issue id, keys maps it to the issue key.
+func (t *tempo) fillWeek(from time.Time, to time.Time, logworkList []types.LogWorkStatus, keys map[string]string) error {
+	path := fmt.Sprintf("/worklogs/user/%s?from=%s&to=%s&limit=1000", url.PathEscape(t.accountID),
+		from.Format(time.DateOnly), to.AddDate(0, 0, -1).Format(time.DateOnly))
+
+	for path != "" {
+		var page struct {
+			Results  []tempoWorklog `json:"results"`
+			Metadata struct {
+				Next string `json:"next"`
+			} `json:"metadata"`
+		}
+		if err := t.client.Do(http.MethodGet, path, "", nil, &page); err != nil {
+			return err
+		}
+
+		for _, w := range page.Results {
+			day, err := time.ParseInLocation(time.DateOnly, w.StartDate, time.Local)
+			if err != nil {
+				continue
+			}
+			status := dayOf(logworkList, day)
+			if status == nil {
+				continue
+			}
+			if started, err := time.ParseInLocation(time.DateTime, w.StartDate+" "+w.StartTime, time.Local); err == nil {
+				status.AddWorklog(started, w.TimeSpentSeconds)
+			} else {
+				status.Add(w.TimeSpentSeconds)
+			}
+			if key, ok := keys[strconv.Itoa(w.Issue.ID)]; ok {
+				status.AddTicketTime(key, w.TimeSpentSeconds)
+			}
+		}
+
+		path = strings.TrimPrefix(page.Metadata.Next, t.client.BaseURL)
+	}
+
+	return nil
+}
+
+// addWorklog creates a Tempo worklog on the Jira issue with numeric id issueID. A nil
+// remaining lets Jira reduce the remaining estimate by the time spent.
+func (t *tempo) addWorklog(issueID int, action types.LogAction, remaining *int64, description string) error {
+	worklog := map[string]interface{}{
+		"issueId":          issueID,
+		"authorAccountId":  t.accountID,
+		"timeSpentSeconds": action.TimeToLog,
+		"startDate":        action.DateToLog.Format(time.DateOnly),
+		"startTime":        action.DateToLog.Format(time.TimeOnly),
+		"attributes":       t.attributes(action.TicketToLog.ID),
+		"description":      description,
+	}
+	if !t.billable(action.TicketToLog.ID) {
+		worklog["billableSeconds"] = 0
+	}
+	if remaining != nil {
+		worklog["remainingEstimateSeconds"] = *remaining
+	}
+
+	return t.client.Do(http.MethodPost, "/worklogs", "", worklog, nil)
+}
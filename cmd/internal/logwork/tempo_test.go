@@ -0,0 +1,41 @@
+package logwork
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+func TestTempoValidateOnlyBlocksLockedPeriods(t *testing.T) {
+	// mỗi ngày là một period riêng với status theo ngày
+	statuses := map[string]string{
+		"2024-05-06": "OPEN",
+		"2024-05-07": "READY_TO_SUBMIT",
+		"2024-05-08": "WAITING_FOR_APPROVAL",
+		"2024-05-09": "APPROVED",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		day := r.URL.Query().Get("from")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"period": {"from": %q, "to": %q}, "status": {"key": %q}}`, day, day, statuses[day])
+	}))
+	defer server.Close()
+
+	tp := newTempo(types.TempoConfig{Endpoint: server.URL}, "me")
+	for day, status := range statuses {
+		date, _ := time.ParseInLocation(time.DateOnly, day, time.Local)
+		err := tp.validate([]types.LogAction{{TicketToLog: types.Ticket{ID: "APP-1"}, DateToLog: date}})
+		locked := status == "WAITING_FOR_APPROVAL" || status == "APPROVED"
+		if locked && (err == nil || !strings.Contains(err.Error(), status)) {
+			t.Errorf("%s: err = %v, want the period reported as %s", day, err, status)
+		}
+		if !locked && err != nil {
+			t.Errorf("%s: err = %v, want a %s period to take worklogs", day, err, status)
+		}
+	}
+}
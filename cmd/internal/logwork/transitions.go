@@ -0,0 +1,233 @@
+package logwork
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// Events a transition rule can react to.
+const (
+	transitionOnFirstWorklog    = "first-worklog"
+	transitionOnWorklog         = "worklog"
+	transitionOnEstimateReached = "estimate-reached"
+	transitionOnEndOfRun        = "end-of-run"
+)
+
+// defaultTransitionRules keeps the historical behaviour: Open issues are paused once logged.
+var defaultTransitionRules = []types.TransitionRule{
+	{On: transitionOnWorklog, FromStatus: "Open", Transition: "PAUSE"},
+}
+
+// transitionRules applies the configured rules during one logwork run.
+type transitionRules struct {
+	rules []types.TransitionRule
+	// logged are the issues that already had a worklog in this run
+	logged map[string]bool
+	// fired remembers the one-off events (estimate-reached, end-of-run) per issue and rule
+	fired map[string]bool
+}
+
+func newTransitionRules(config []types.TransitionRule) (*transitionRules, error) {
+	rules := config
+	if rules == nil {
+		rules = defaultTransitionRules
+	}
+
+	for i, rule := range rules {
+		switch rule.On {
+		case transitionOnFirstWorklog, transitionOnWorklog, transitionOnEstimateReached, transitionOnEndOfRun:
+		default:
+			return nil, fmt.Errorf("transition rule %d: invalid event %q, expected first-worklog, worklog, estimate-reached or end-of-run", i+1, rule.On)
+		}
+		if rule.Transition == "" {
+			return nil, fmt.Errorf("transition rule %d: no transition name or ID", i+1)
+		}
+	}
+
+	return &transitionRules{rules: rules, logged: map[string]bool{}, fired: map[string]bool{}}, nil
+}
+
+// worklogEvents returns the events raised by a worklog on an issue, in the order their rules
+// are tried.
+func (r *transitionRules) worklogEvents(ticketID string, state issueState) []string {
+	events := []string{}
+	if !r.logged[ticketID] {
+		r.logged[ticketID] = true
+		events = append(events, transitionOnFirstWorklog)
+	}
+	events = append(events, transitionOnWorklog)
+	if state.estimate > 0 && state.spent >= state.estimate {
+		events = append(events, transitionOnEstimateReached)
+	}
+	return events
+}
+
+// next returns the first rule for event matching the state of the issue, nil if none.
+func (r *transitionRules) next(ticketID string, event string, state issueState) *types.TransitionRule {
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.On != event || !matchesStatus(*rule, state) {
+			continue
+		}
+		// các event một lần chỉ chạy một lần cho mỗi issue
+		key := fmt.Sprintf("%s/%d", ticketID, i)
+		if event != transitionOnWorklog && r.fired[key] {
+			continue
+		}
+		r.fired[key] = true
+		return rule
+	}
+	return nil
+}
+
+func matchesStatus(rule types.TransitionRule, state issueState) bool {
+	if rule.FromCategory != "" && !strings.EqualFold(rule.FromCategory, state.categoryKey) && !strings.EqualFold(rule.FromCategory, state.category) {
+		return false
+	}
+	if rule.FromStatus != "" && !strings.EqualFold(rule.FromStatus, state.status) {
+		return false
+	}
+	return true
+}
+
+// findTransition returns the transition of the rule among the available ones and the
+// required screen fields the rule does not fill.
+func findTransition(rule types.TransitionRule, transitions []jira.Transition) (*jira.Transition, []string) {
+	for i := range transitions {
+		t := &transitions[i]
+		if t.ID != rule.Transition && !strings.EqualFold(t.Name, rule.Transition) {
+			continue
+		}
+		missing := []string{}
+		for name, field := range t.Fields {
+			if _, ok := rule.Fields[name]; field.Required && !ok {
+				missing = append(missing, name)
+			}
+		}
+		return t, missing
+	}
+	return nil, nil
+}
+
+// runTransitions applies the rules of the given events to an issue. The issue is re-read
+// after every transition so the next rules see the new status.
+func runTransitions(issues *issueCache, rules *transitionRules, ticketID string, events []string) {
+	state, err := issues.get(ticketID)
+	if err != nil {
+		log.Printf("⚠️  %v\n", err)
+		return
+	}
+
+	for _, event := range events {
+		for rule := rules.next(ticketID, event, *state); rule != nil; rule = rules.next(ticketID, event, *state) {
+			transitions, err := issues.transitionsOf(ticketID)
+			if err != nil {
+				log.Printf("⚠️  %v\n", err)
+				return
+			}
+			t, missing := findTransition(*rule, transitions)
+			if t == nil {
+				log.Printf("⚠️  No '%s' transition found for issue %s (%s)\n", rule.Transition, ticketID, state.status)
+				break
+			}
+			if len(missing) > 0 {
+				log.Printf("⚠️  Transition '%s' of %s needs the fields %s\n", t.Name, ticketID, strings.Join(missing, ", "))
+				break
+			}
+
+			payload := map[string]interface{}{"transition": map[string]string{"id": t.ID}}
+			if len(rule.Fields) > 0 {
+				payload["fields"] = rule.Fields
+			}
+			if _, err := issues.client.Issue.DoTransitionWithPayload(ticketID, payload); err != nil {
+				log.Printf("❌ Failed to move issue %s to %s: %v\n", ticketID, t.To.Name, err)
+				break
+			}
+			fmt.Printf("🟡 Issue %s transitioned to '%s' (%s)\n", ticketID, t.To.Name, event)
+
+			if state, err = issues.refresh(ticketID); err != nil {
+				log.Printf("⚠️  %v\n", err)
+				return
+			}
+			if event == transitionOnWorklog {
+				// worklog rule chạy một lần cho mỗi worklog, tránh lặp vô hạn
+				break
+			}
+		}
+	}
+}
+
+// printTransitionPlan shows which transitions the rules would run for the plan, from the
+// current state of every issue. Once an issue has moved, its next transitions are only
+// known by name.
+func printTransitionPlan(issues *issueCache, logActionList []types.LogAction, rules []types.TransitionRule) {
+	fmt.Println("----------------Transitions-------------------")
+
+	plan, err := newTransitionRules(rules)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// trạng thái giả lập, không đụng vào cache
+	type simulated struct {
+		issueState
+		known bool
+	}
+	states := map[string]*simulated{}
+	order := []string{}
+	simulate := func(ticketID string, events []string) {
+		state := states[ticketID]
+		for _, event := range events {
+			for rule := plan.next(ticketID, event, state.issueState); rule != nil; rule = plan.next(ticketID, event, state.issueState) {
+				if !state.known {
+					fmt.Printf("Ticket ID: %s\t%s: %s -> '%s' if available\n", ticketID, event, state.status, rule.Transition)
+					break
+				}
+				t, missing := findTransition(*rule, state.transitions)
+				if t == nil {
+					fmt.Printf("Ticket ID: %s\t%s: no '%s' transition from %s\n", ticketID, event, rule.Transition, state.status)
+					break
+				}
+				if len(missing) > 0 {
+					fmt.Printf("Ticket ID: %s\t%s: '%s' needs the fields %s\n", ticketID, event, t.Name, strings.Join(missing, ", "))
+					break
+				}
+				fmt.Printf("Ticket ID: %s\t%s: %s -> %s ('%s')\n", ticketID, event, state.status, t.To.Name, t.Name)
+				state.status, state.categoryKey, state.category = t.To.Name, t.To.StatusCategory.Key, t.To.StatusCategory.Name
+				state.known = false
+				if event == transitionOnWorklog {
+					break
+				}
+			}
+		}
+	}
+
+	for _, action := range logActionList {
+		id := action.TicketToLog.ID
+		if _, ok := states[id]; !ok {
+			state := &simulated{}
+			if current, err := issues.get(id); err != nil {
+				fmt.Printf(" ⚠️  %v\n", err)
+			} else if transitions, err := issues.transitionsOf(id); err != nil {
+				fmt.Printf(" ⚠️  %v\n", err)
+				state.issueState = *current
+			} else {
+				state.issueState = *current
+				state.transitions = transitions
+				state.known = true
+			}
+			states[id] = state
+			order = append(order, id)
+		}
+		states[id].spent += action.TimeToLog
+		simulate(id, plan.worklogEvents(id, states[id].issueState))
+	}
+	for _, id := range order {
+		simulate(id, []string{transitionOnEndOfRun})
+	}
+}
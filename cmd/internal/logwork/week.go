@@ -0,0 +1,80 @@
+package logwork
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// period is the range of days a run logs, [from, to). The zero period is the current week.
+type period struct {
+	from time.Time
+	to   time.Time
+}
+
+// newPeriod reads --from / --to from the config, To being the last day logged.
+func newPeriod(config *types.Config) period {
+	if config.From.IsZero() {
+		return period{}
+	}
+	return period{from: startOfDay(config.From), to: startOfDay(config.To).AddDate(0, 0, 1)}
+}
+
+// days returns the first day of the period, the day after the last one and a worklog
+// status per day, in date order.
+func (p period) days() (time.Time, time.Time, []types.LogWorkStatus) {
+	from, to := p.from, p.to
+	if from.IsZero() {
+		from = currentWeek()
+		to = from.AddDate(0, 0, 7)
+	}
+
+	logworkList := []types.LogWorkStatus{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		logworkList = append(logworkList, types.LogWorkStatus{Date: day})
+	}
+	return from, to, logworkList
+}
+
+// currentWeek returns the Monday of the current week.
+func currentWeek() time.Time {
+	now := time.Now()
+	start := startOfDay(now)
+
+	// Sunday is 0 -> we need to handle this
+	if now.Weekday() == time.Sunday {
+		return start.AddDate(0, 0, -6)
+	}
+	return start.AddDate(0, 0, -int(now.Weekday())+1)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// dayOf returns the worklog status of the day t falls on (in t's location), nil when the
+// day is not in the list.
+func dayOf(logworkList []types.LogWorkStatus, t time.Time) *types.LogWorkStatus {
+	y, m, d := t.Date()
+	for i := range logworkList {
+		dy, dm, dd := logworkList[i].Date.Date()
+		if y == dy && m == dm && d == dd {
+			return &logworkList[i]
+		}
+	}
+	return nil
+}
+
+// printPeriod prints the first and last day of the run.
+func printPeriod(from time.Time, to time.Time) {
+	fmt.Printf("From %s to %s\n", from.Format(time.DateOnly), to.AddDate(0, 0, -1).Format(time.DateOnly))
+}
+
+// printWeekStatus prints the time already logged on each day of the run.
+func printWeekStatus(logworkList []types.LogWorkStatus) {
+	for _, day := range logworkList {
+		fmt.Printf("%s %s: Time Spent: %s\n", day.Date.Weekday(), day.Date.Format(time.DateOnly), helper.FormatEstimate(day.TimeSpent))
+	}
+}
@@ -0,0 +1,426 @@
+package logwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/rest"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+const (
+	youTrackDefaultQuery      = "for: me #Unresolved"
+	youTrackDefaultEstimation = "Estimation"
+	youTrackDefaultSpentTime  = "Spent time"
+	youTrackIssueFields       = "idReadable,summary,created,updated,project(shortName),tags(name),parent(issues(idReadable)),customFields(name,value(name,minutes))"
+)
+
+type YouTrack struct {
+	endpoint   string
+	userName   string
+	apiToken   string
+	config     types.YouTrackConfig
+	dryRun     bool
+	allocation types.AllocationConfig
+	worklog    types.WorklogConfig
+	client     *rest.Client
+	estimation types.EstimationConfig
+	// yes accepts the estimate suggestions without review, see reviewSuggestions
+	yes bool
+	// days are the days of the run, --from / --to or the current week
+	days period
+}
+
+type youTrackIssue struct {
+	IDReadable string `json:"idReadable"`
+	Summary    string `json:"summary"`
+	Created    int64  `json:"created"`
+	Updated    int64  `json:"updated"`
+	Project    struct {
+		ShortName string `json:"shortName"`
+	} `json:"project"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Parent struct {
+		Issues []struct {
+			IDReadable string `json:"idReadable"`
+		} `json:"issues"`
+	} `json:"parent"`
+	CustomFields []struct {
+		Name  string          `json:"name"`
+		Value json.RawMessage `json:"value"`
+	} `json:"customFields"`
+}
+
+// youTrackFieldValue covers the single-value custom fields we read: periods and enums/states.
+type youTrackFieldValue struct {
+	Name    string `json:"name"`
+	Minutes int64  `json:"minutes"`
+}
+
+type youTrackWorkItem struct {
+	Date  int64 `json:"date"`
+	Issue struct {
+		IDReadable string `json:"idReadable"`
+	} `json:"issue"`
+	Duration struct {
+		Minutes int64 `json:"minutes"`
+	} `json:"duration"`
+}
+
+// NewYouTrack expects the endpoint as the YouTrack base URL (e.g. https://acme.youtrack.cloud)
+// and a permanent token as apiToken.
+func NewYouTrack(config *types.Config) *YouTrack {
+	youTrack := config.YouTrack
+	if youTrack.Query == "" {
+		youTrack.Query = youTrackDefaultQuery
+	}
+	if youTrack.EstimationField == "" {
+		youTrack.EstimationField = youTrackDefaultEstimation
+	}
+	if youTrack.SpentTimeField == "" {
+		youTrack.SpentTimeField = youTrackDefaultSpentTime
+	}
+
+	return &YouTrack{
+		endpoint:   config.Endpoint,
+		userName:   config.Username,
+		apiToken:   config.ApiToken,
+		config:     youTrack,
+		dryRun:     config.DryRun,
+		allocation: config.Allocation,
+		worklog:    config.Worklog,
+		estimation: config.Estimation,
+		yes:        config.Yes,
+		days:       newPeriod(config),
+		client:     rest.NewClient(config.Endpoint, http.Header{"Authorization": []string{"Bearer " + config.ApiToken}}),
+	}
+}
+
+func (y *YouTrack) searchIssues(query string, top int) ([]youTrackIssue, error) {
+	issues := []youTrackIssue{}
+	path := fmt.Sprintf("/api/issues?query=%s&fields=%s&$top=%d", url.QueryEscape(query), youTrackIssueFields, top)
+	if err := y.client.Do(http.MethodGet, path, "", nil, &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func (y *YouTrack) getIssue(id string) (*youTrackIssue, error) {
+	issue := &youTrackIssue{}
+	path := fmt.Sprintf("/api/issues/%s?fields=%s", url.PathEscape(id), youTrackIssueFields)
+	if err := y.client.Do(http.MethodGet, path, "", nil, issue); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+func (i *youTrackIssue) field(name string) youTrackFieldValue {
+	value := youTrackFieldValue{}
+	for _, f := range i.CustomFields {
+		if strings.EqualFold(f.Name, name) {
+			// multi-value fields are arrays and are not needed here
+			_ = json.Unmarshal(f.Value, &value)
+			break
+		}
+	}
+	return value
+}
+
+func (y *YouTrack) toTicket(i *youTrackIssue) types.Ticket {
+	ticket := types.Ticket{
+		ID:              i.IDReadable,
+		Summary:         i.Summary,
+		Est:             i.field(y.config.EstimationField).Minutes * 60,
+		EstimatedLogged: i.field(y.config.SpentTimeField).Minutes * 60,
+		Status:          i.field("State").Name,
+		Type:            i.field("Type").Name,
+		Project:         i.Project.ShortName,
+		Created:         jira.Time(time.UnixMilli(i.Created)),
+		Updated:         jira.Time(time.UnixMilli(i.Updated)),
+	}
+	if len(i.Parent.Issues) > 0 {
+		ticket.Parent = i.Parent.Issues[0].IDReadable
+	}
+	for _, tag := range i.Tags {
+		ticket.Labels = append(ticket.Labels, tag.Name)
+	}
+	return ticket
+}
+
+// workTypeID resolves the configured work item type name to its ID, "" means no type.
+func (y *YouTrack) workTypeID() (string, error) {
+	if y.config.WorkType == "" {
+		return "", nil
+	}
+
+	workTypes := []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}{}
+	if err := y.client.Do(http.MethodGet, "/api/admin/timeTrackingSettings/workItemTypes?fields=id,name", "", nil, &workTypes); err != nil {
+		return "", err
+	}
+
+	for _, w := range workTypes {
+		if strings.EqualFold(w.Name, y.config.WorkType) {
+			return w.ID, nil
+		}
+	}
+	return "", fmt.Errorf("work item type %q not found in YouTrack", y.config.WorkType)
+}
+
+func (y *YouTrack) GetTicketToLog() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket able to log-------------------")
+
+	issues, err := y.searchIssues(y.config.Query, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching YouTrack issues: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
+	for i := range issues {
+		ticket := y.toTicket(&issues[i])
+		fmt.Printf("Issue: %s, Summary %s, Est: %s, Status: %s\n", ticket.ID, ticket.Summary, helper.FormatEstimate(ticket.Est), ticket.Status)
+		ticketList = append(ticketList, ticket)
+	}
+	return ticketList, nil
+}
+
+func (y *YouTrack) GetDayToLog() ([]types.LogWorkStatus, error) {
+	from, to, logworkList := y.days.days()
+
+	fmt.Println("----------------Your week worklog status-------------------")
+	printPeriod(from, to)
+
+	workItems := []youTrackWorkItem{}
+	path := fmt.Sprintf("/api/workItems?author=me&startDate=%s&endDate=%s&fields=date,duration(minutes),issue(idReadable)&$top=1000",
+		from.Format(time.DateOnly), to.AddDate(0, 0, -1).Format(time.DateOnly))
+	if err := y.client.Do(http.MethodGet, path, "", nil, &workItems); err != nil {
+		return nil, fmt.Errorf("error fetching YouTrack work items: %v", err)
+	}
+
+	fmt.Println("Work logs of the period:")
+
+	for _, w := range workItems {
+		// YouTrack stores the work item date as midnight UTC of the reported day
+		if day := dayOf(logworkList, time.UnixMilli(w.Date).UTC()); day != nil {
+			day.Add(w.Duration.Minutes * 60)
+			day.AddTicketTime(w.Issue.IDReadable, w.Duration.Minutes*60)
+		}
+	}
+
+	printWeekStatus(logworkList)
+
+	return logworkList, nil
+}
+
+func (y *YouTrack) FillEstimate(ticket []types.Ticket) error {
+	return nil
+}
+
+func (y *YouTrack) LogWork(ticket []types.Ticket, logworkList []types.LogWorkStatus) error {
+	logActionList, err := defaultLogWorkAlgorithm(ticket, logworkList, y.allocation)
+	if err != nil {
+		return err
+	}
+	comments, err := renderComments(logActionList, y.worklog.Comment)
+	if err != nil {
+		return err
+	}
+
+	printLogActions(logActionList)
+
+	ok, err := confirmPlan(y.dryRun)
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, raise := range raisedEstimates(logActionList) {
+		if err := y.raiseEstimate(raise.TicketToLog.ID, raise.TimeToLog); err != nil {
+			log.Fatalf("Failed to raise estimate of %s: %v", raise.TicketToLog.ID, err)
+		}
+		fmt.Printf("🔺 Raised estimate of %s by %s\n", raise.TicketToLog.ID, helper.FormatEstimate(raise.TimeToLog))
+	}
+
+	workTypeID, err := y.workTypeID()
+	if err != nil {
+		return err
+	}
+
+	for i, action := range logActionList {
+		if err := y.addWorkItem(action, comments[i], workTypeID); err != nil {
+			log.Fatalf("Failed to log work: %v", err)
+		}
+
+		fmt.Printf("Work logged to issue %s: %s successfully.\n", action.TicketToLog.ID, action.TicketToLog.Summary)
+	}
+
+	return nil
+}
+
+// addWorkItem posts one worklog as a work item of the issue, workTypeID "" means no type.
+func (y *YouTrack) addWorkItem(action types.LogAction, text string, workTypeID string) error {
+	workItem := map[string]interface{}{
+		"date": action.DateToLog.UnixMilli(),
+		// YouTrack chỉ nhận phút, làm tròn thay vì cắt bỏ phần giây
+		"duration": map[string]interface{}{"minutes": (action.TimeToLog + 30) / 60},
+		"text":     text,
+	}
+	if workTypeID != "" {
+		workItem["type"] = map[string]interface{}{"id": workTypeID}
+	}
+
+	path := fmt.Sprintf("/api/issues/%s/timeTracking/workItems", url.PathEscape(action.TicketToLog.ID))
+	return y.client.Do(http.MethodPost, path, "", workItem, nil)
+}
+
+// GetTicketToEst fetches the user's issues and, for issues without estimation, searches
+// YouTrack for issues with a similar summary that have one.
+func (y *YouTrack) GetTicketToEst() ([]types.Ticket, error) {
+	fmt.Println("----------------Ticket need to estimate (searching whole YouTrack)-------------------")
+
+	rules, err := newEstimateRules(y.estimation.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := y.searchIssues(y.config.Query, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user issues: %v", err)
+	}
+
+	ticketList := []types.Ticket{}
+	for i := range issues {
+		ticketList = append(ticketList, y.toTicket(&issues[i]))
+	}
+
+	fmt.Printf("Fetched %d tickets assigned to %s\n", len(ticketList), y.userName)
+
+	fmt.Println("\n----------------Auto-fill estimate by searching-------------------")
+
+	suggestions := []suggestion{}
+	for idx := range ticketList {
+		t := &ticketList[idx]
+		if t.Est > 0 {
+			continue
+		}
+
+		// rule trong config được ưu tiên trước khi tìm theo tiêu đề
+		if rule := matchEstimateRule(rules, *t); rule != nil {
+			applyEstimateRule(t, rule)
+			continue
+		}
+
+		fmt.Printf("Searching matches for: %s (%s)\n", t.ID, t.Summary)
+
+		keywords := helper.ExtractKeywords(t.Summary, 3)
+		if len(keywords) == 0 {
+			fmt.Printf(" ⚠️  No useful keywords found for %s, skipping\n", t.ID)
+			continue
+		}
+
+		clauses := []string{}
+		for _, kw := range keywords {
+			clauses = append(clauses, "summary: "+kw)
+		}
+		query := fmt.Sprintf("(%s) and has: {%s} sort by: created desc", strings.Join(clauses, " or "), y.config.EstimationField)
+
+		candidates, err := y.searchIssues(query, 500)
+		if err != nil {
+			log.Printf(" ⚠️  Error searching YouTrack for %s: %v\n", t.ID, err)
+			continue
+		}
+
+		if len(candidates) == 0 {
+			fmt.Printf(" ❌  No candidates found in YouTrack for %s\n", t.ID)
+			continue
+		}
+
+		candidateTickets := []types.Ticket{}
+		for i := range candidates {
+			candidateTickets = append(candidateTickets, y.toTicket(&candidates[i]))
+		}
+		if s, ok := suggestByTitle(ticketList, idx, candidateTickets); ok {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	if err := reviewSuggestions(ticketList, suggestions, y.yes, autoAcceptScore(y.estimation)); err != nil {
+		return nil, err
+	}
+
+	return ticketList, nil
+}
+
+// addComment posts a comment on an issue.
+func (y *YouTrack) addComment(id string, text string) error {
+	return y.client.Do(http.MethodPost, fmt.Sprintf("/api/issues/%s/comments", url.PathEscape(id)), "", map[string]string{"text": text}, nil)
+}
+
+// setEstimate sets the estimation field of an issue to seconds.
+func (y *YouTrack) setEstimate(id string, seconds int64) error {
+	update := map[string]interface{}{
+		"customFields": []map[string]interface{}{{
+			"name":  y.config.EstimationField,
+			"$type": "PeriodIssueCustomField",
+			"value": map[string]interface{}{"minutes": seconds / 60},
+		}},
+	}
+	path := fmt.Sprintf("/api/issues/%s?fields=idReadable", url.PathEscape(id))
+	return y.client.Do(http.MethodPost, path, "", update, nil)
+}
+
+// raiseEstimate grows the estimation field of an issue by seconds.
+func (y *YouTrack) raiseEstimate(id string, seconds int64) error {
+	issue, err := y.getIssue(id)
+	if err != nil {
+		return err
+	}
+	return y.setEstimate(id, issue.field(y.config.EstimationField).Minutes*60+seconds)
+}
+
+func (y *YouTrack) AddEstForTicket(ticketList []types.Ticket) error {
+	fmt.Println("\n----------------Updating estimate to YouTrack-------------------")
+
+	for _, t := range ticketList {
+		// chỉ update cho task open và có estimate hợp lệ
+		if !strings.EqualFold(t.Status, "Open") || t.Est <= 0 {
+			continue
+		}
+
+		issue, err := y.getIssue(t.ID)
+		if err != nil {
+			fmt.Printf(" ⚠️  Cannot fetch issue %s: %v\n", t.ID, err)
+			continue
+		}
+
+		if est := issue.field(y.config.EstimationField).Minutes * 60; est > 0 {
+			fmt.Printf("⏭️ %s đã có estimate (%s), bỏ qua\n", t.ID, helper.FormatEstimate(est))
+			continue
+		}
+
+		if err := y.setEstimate(t.ID, t.Est); err != nil {
+			fmt.Printf("❌Update fail %s (%s): %v\n", t.ID, t.Summary, err)
+			continue
+		}
+
+		fmt.Printf("✅ Updated estimate %s -> %s (%s)\n", t.ID, helper.FormatEstimate(t.Est), t.EstimateSource)
+
+		if t.EstimateSource != "" {
+			if err := y.addComment(t.ID, estimateNote(t)); err != nil {
+				fmt.Printf(" ⚠️  Cannot comment the estimate source on %s: %v\n", t.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
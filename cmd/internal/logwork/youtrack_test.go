@@ -0,0 +1,453 @@
+package logwork
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/helper"
+	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
+)
+
+// youTrackStandIn is a local HTTP stand-in for the YouTrack REST API. handlers are keyed by
+// "METHOD /path", requests keeps every request received with its body.
+type youTrackStandIn struct {
+	server   *httptest.Server
+	handlers map[string]http.HandlerFunc
+	requests []youTrackRequest
+}
+
+type youTrackRequest struct {
+	method string
+	path   string
+	query  map[string][]string
+	body   string
+}
+
+func newYouTrackStandIn(t *testing.T) *youTrackStandIn {
+	s := &youTrackStandIn{handlers: map[string]http.HandlerFunc{}}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer perm-token" {
+			t.Errorf("%s %s: Authorization = %q", r.Method, r.URL.Path, got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.requests = append(s.requests, youTrackRequest{method: r.Method, path: r.URL.Path, query: r.URL.Query(), body: string(body)})
+
+		handler, ok := s.handlers[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *youTrackStandIn) handle(route string, status int, body string) {
+	s.handlers[route] = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+// received returns the requests received for route.
+func (s *youTrackStandIn) received(route string) []youTrackRequest {
+	found := []youTrackRequest{}
+	for _, r := range s.requests {
+		if r.method+" "+r.path == route {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+func (s *youTrackStandIn) youTrack(config types.YouTrackConfig) *YouTrack {
+	return NewYouTrack(&types.Config{
+		EndpointType: "youtrack",
+		Endpoint:     s.server.URL + "/",
+		Username:     "hieu",
+		ApiToken:     "perm-token",
+		YouTrack:     config,
+	})
+}
+
+const youTrackIssuesJSON = `[
+	{
+		"idReadable": "APP-1",
+		"summary": "Login page",
+		"created": 1760000000000,
+		"updated": 1760100000000,
+		"project": {"shortName": "APP"},
+		"tags": [{"name": "frontend"}, {"name": "auth"}],
+		"parent": {"issues": [{"idReadable": "APP-0"}]},
+		"customFields": [
+			{"name": "Estimation", "value": {"minutes": 240}},
+			{"name": "Spent time", "value": {"minutes": 90}},
+			{"name": "State", "value": {"name": "In Progress"}},
+			{"name": "Type", "value": {"name": "Task"}},
+			{"name": "Assignee", "value": [{"name": "hieu"}]}
+		]
+	},
+	{
+		"idReadable": "APP-2",
+		"summary": "Logout button",
+		"created": 1760000000000,
+		"updated": 1760000000000,
+		"project": {"shortName": "APP"},
+		"tags": [],
+		"parent": {"issues": []},
+		"customFields": [
+			{"name": "Estimation", "value": null},
+			{"name": "State", "value": {"name": "Open"}}
+		]
+	}
+]`
+
+func TestYouTrackGetTicketToLog(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/issues", http.StatusOK, youTrackIssuesJSON)
+
+	tickets, err := s.youTrack(types.YouTrackConfig{}).GetTicketToLog()
+	if err != nil {
+		t.Fatalf("GetTicketToLog: %v", err)
+	}
+
+	requests := s.received("GET /api/issues")
+	if len(requests) != 1 {
+		t.Fatalf("got %d issue searches, want 1", len(requests))
+	}
+	if got := requests[0].query["query"]; len(got) != 1 || got[0] != youTrackDefaultQuery {
+		t.Errorf("query = %v, want the default query %q", got, youTrackDefaultQuery)
+	}
+
+	if len(tickets) != 2 {
+		t.Fatalf("got %d tickets, want 2", len(tickets))
+	}
+	got := tickets[0]
+	if got.ID != "APP-1" || got.Summary != "Login page" || got.Project != "APP" || got.Parent != "APP-0" {
+		t.Errorf("ticket = %+v", got)
+	}
+	if got.Est != 4*3600 || got.EstimatedLogged != 90*60 {
+		t.Errorf("Est = %d, EstimatedLogged = %d, want %d and %d", got.Est, got.EstimatedLogged, 4*3600, 90*60)
+	}
+	if got.Status != "In Progress" || got.Type != "Task" {
+		t.Errorf("Status = %q, Type = %q", got.Status, got.Type)
+	}
+	if strings.Join(got.Labels, ",") != "frontend,auth" {
+		t.Errorf("Labels = %v", got.Labels)
+	}
+	if !time.Time(got.Created).Equal(time.UnixMilli(1760000000000)) {
+		t.Errorf("Created = %v", time.Time(got.Created))
+	}
+	if tickets[1].Est != 0 || tickets[1].Parent != "" {
+		t.Errorf("ticket without estimation = %+v", tickets[1])
+	}
+}
+
+func TestYouTrackGetTicketToLogCustomFields(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/issues", http.StatusOK, `[{"idReadable": "APP-3", "customFields": [
+		{"name": "Original estimate", "value": {"minutes": 60}},
+		{"name": "Estimation", "value": {"minutes": 999}}
+	]}]`)
+
+	tickets, err := s.youTrack(types.YouTrackConfig{Query: "project: APP", EstimationField: "original estimate"}).GetTicketToLog()
+	if err != nil {
+		t.Fatalf("GetTicketToLog: %v", err)
+	}
+	if got := s.received("GET /api/issues")[0].query["query"]; got[0] != "project: APP" {
+		t.Errorf("query = %v, want the configured query", got)
+	}
+	if tickets[0].Est != 3600 {
+		t.Errorf("Est = %d, want the configured estimation field (3600)", tickets[0].Est)
+	}
+}
+
+func TestYouTrackGetTicketToLogError(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/issues", http.StatusUnauthorized, `{"error":"Unauthorized"}`)
+
+	_, err := s.youTrack(types.YouTrackConfig{}).GetTicketToLog()
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("err = %v, want the 401 of YouTrack", err)
+	}
+}
+
+func TestYouTrackGetDayToLog(t *testing.T) {
+	startOfWeek := currentWeek()
+	// YouTrack trả về date của work item là nửa đêm UTC của ngày được log
+	day := func(offset int) int64 {
+		d := startOfWeek.AddDate(0, 0, offset)
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
+	}
+
+	s := newYouTrackStandIn(t)
+	items, _ := json.Marshal([]map[string]interface{}{
+		{"date": day(0), "duration": map[string]interface{}{"minutes": 120}, "issue": map[string]interface{}{"idReadable": "APP-1"}},
+		{"date": day(0), "duration": map[string]interface{}{"minutes": 30}, "issue": map[string]interface{}{"idReadable": "APP-2"}},
+		{"date": day(2), "duration": map[string]interface{}{"minutes": 480}, "issue": map[string]interface{}{"idReadable": "APP-1"}},
+	})
+	s.handle("GET /api/workItems", http.StatusOK, string(items))
+
+	logworkList, err := s.youTrack(types.YouTrackConfig{}).GetDayToLog()
+	if err != nil {
+		t.Fatalf("GetDayToLog: %v", err)
+	}
+
+	query := s.received("GET /api/workItems")[0].query
+	if query["author"][0] != "me" {
+		t.Errorf("author = %v, want me", query["author"])
+	}
+	if query["startDate"][0] != startOfWeek.Format(time.DateOnly) || query["endDate"][0] != startOfWeek.AddDate(0, 0, 6).Format(time.DateOnly) {
+		t.Errorf("startDate = %v, endDate = %v, want the current week", query["startDate"], query["endDate"])
+	}
+
+	want := map[time.Weekday]int64{time.Monday: 150 * 60, time.Wednesday: 8 * 3600}
+	for _, status := range logworkList {
+		if status.TimeSpent != want[status.Date.Weekday()] {
+			t.Errorf("%s: TimeSpent = %d, want %d", status.Date.Weekday(), status.TimeSpent, want[status.Date.Weekday()])
+		}
+	}
+	if monday := logworkList[0].Tickets; monday["APP-1"] != 120*60 || monday["APP-2"] != 30*60 {
+		t.Errorf("Monday per ticket = %v, want APP-1 2h and APP-2 30m", monday)
+	}
+}
+
+func TestYouTrackGetDayToLogError(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/workItems", http.StatusInternalServerError, `{"error":"server_error"}`)
+
+	if _, err := s.youTrack(types.YouTrackConfig{}).GetDayToLog(); err == nil {
+		t.Fatal("GetDayToLog succeeded on a 500")
+	}
+}
+
+func TestYouTrackAddWorkItem(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/admin/timeTrackingSettings/workItemTypes", http.StatusOK, `[{"id": "58-0", "name": "Testing"}, {"id": "58-1", "name": "Development"}]`)
+	s.handle("POST /api/issues/APP-1/timeTracking/workItems", http.StatusOK, `{}`)
+
+	y := s.youTrack(types.YouTrackConfig{WorkType: "development"})
+	workTypeID, err := y.workTypeID()
+	if err != nil {
+		t.Fatalf("workTypeID: %v", err)
+	}
+	if workTypeID != "58-1" {
+		t.Fatalf("workTypeID = %q, want 58-1", workTypeID)
+	}
+
+	date := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	action := types.LogAction{TimeToLog: 90 * 60, DateToLog: date, TicketToLog: types.Ticket{ID: "APP-1"}}
+	if err := y.addWorkItem(action, "Login page", workTypeID); err != nil {
+		t.Fatalf("addWorkItem: %v", err)
+	}
+
+	posts := s.received("POST /api/issues/APP-1/timeTracking/workItems")
+	if len(posts) != 1 {
+		t.Fatalf("got %d work item posts, want 1", len(posts))
+	}
+	workItem := struct {
+		Date     int64 `json:"date"`
+		Duration struct {
+			Minutes int64 `json:"minutes"`
+		} `json:"duration"`
+		Text string `json:"text"`
+		Type *struct {
+			ID string `json:"id"`
+		} `json:"type"`
+	}{}
+	if err := json.Unmarshal([]byte(posts[0].body), &workItem); err != nil {
+		t.Fatalf("work item body %s: %v", posts[0].body, err)
+	}
+	if workItem.Date != date.UnixMilli() || workItem.Duration.Minutes != 90 || workItem.Text != "Login page" {
+		t.Errorf("work item = %+v", workItem)
+	}
+	if workItem.Type == nil || workItem.Type.ID != "58-1" {
+		t.Errorf("work item type = %+v, want 58-1", workItem.Type)
+	}
+
+	// 25m 40s được làm tròn lên 26 phút
+	action.TimeToLog = 25*60 + 40
+	if err := y.addWorkItem(action, "Login page", workTypeID); err != nil {
+		t.Fatalf("addWorkItem: %v", err)
+	}
+	posts = s.received("POST /api/issues/APP-1/timeTracking/workItems")
+	if err := json.Unmarshal([]byte(posts[1].body), &workItem); err != nil {
+		t.Fatalf("work item body %s: %v", posts[1].body, err)
+	}
+	if workItem.Duration.Minutes != 26 {
+		t.Errorf("duration = %d minutes, want 26", workItem.Duration.Minutes)
+	}
+}
+
+func TestYouTrackAddWorkItemWithoutType(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("POST /api/issues/APP-1/timeTracking/workItems", http.StatusOK, `{}`)
+
+	y := s.youTrack(types.YouTrackConfig{})
+	workTypeID, err := y.workTypeID()
+	if err != nil || workTypeID != "" {
+		t.Fatalf("workTypeID = %q, %v, want no type and no request", workTypeID, err)
+	}
+	if err := y.addWorkItem(types.LogAction{TimeToLog: 3600, TicketToLog: types.Ticket{ID: "APP-1"}}, "", ""); err != nil {
+		t.Fatalf("addWorkItem: %v", err)
+	}
+	if body := s.received("POST /api/issues/APP-1/timeTracking/workItems")[0].body; strings.Contains(body, `"type"`) {
+		t.Errorf("work item body %s has a type", body)
+	}
+}
+
+func TestYouTrackAddWorkItemErrors(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/admin/timeTrackingSettings/workItemTypes", http.StatusOK, `[{"id": "58-0", "name": "Testing"}]`)
+	s.handle("POST /api/issues/APP-1/timeTracking/workItems", http.StatusBadRequest, `{"error":"bad_request","error_description":"Time tracking is disabled"}`)
+
+	y := s.youTrack(types.YouTrackConfig{WorkType: "Development"})
+	if _, err := y.workTypeID(); err == nil || !strings.Contains(err.Error(), "Development") {
+		t.Errorf("workTypeID err = %v, want an unknown work type error", err)
+	}
+
+	err := y.addWorkItem(types.LogAction{TimeToLog: 3600, TicketToLog: types.Ticket{ID: "APP-1"}}, "", "")
+	if err == nil || !strings.Contains(err.Error(), "Time tracking is disabled") {
+		t.Fatalf("addWorkItem err = %v, want the 400 of YouTrack", err)
+	}
+}
+
+func TestYouTrackAddEstForTicket(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/issues/APP-2", http.StatusOK, `{"idReadable": "APP-2", "customFields": [{"name": "Estimation", "value": null}]}`)
+	s.handle("POST /api/issues/APP-2", http.StatusOK, `{"idReadable": "APP-2"}`)
+	s.handle("POST /api/issues/APP-2/comments", http.StatusOK, `{}`)
+	s.handle("GET /api/issues/APP-1", http.StatusOK, `{"idReadable": "APP-1", "customFields": [{"name": "Estimation", "value": {"minutes": 240}}]}`)
+	s.handle("GET /api/issues/APP-3", http.StatusOK, `{"idReadable": "APP-3", "customFields": []}`)
+	s.handle("POST /api/issues/APP-3", http.StatusBadRequest, `{"error":"bad_request"}`)
+	s.handle("GET /api/issues/APP-5", http.StatusOK, `{"idReadable": "APP-5", "customFields": []}`)
+
+	err := s.youTrack(types.YouTrackConfig{}).AddEstForTicket([]types.Ticket{
+		{ID: "APP-9", Status: "Open", Est: 3600},
+		{ID: "APP-3", Status: "Open", Est: 3600},
+		{ID: "APP-1", Status: "Open", Est: 3600},
+		{ID: "APP-2", Status: "open", Est: 2*3600 + 30*60, EstimateSource: "rule review"},
+		{ID: "APP-4", Status: "Open"},
+		{ID: "APP-5", Status: "In Progress", Est: 3600},
+	})
+	if err != nil {
+		t.Fatalf("AddEstForTicket: %v", err)
+	}
+
+	// APP-9 không tồn tại, APP-3 lỗi khi ghi, APP-1 đã có estimate: chỉ APP-2 được cập nhật
+	if len(s.received("POST /api/issues/APP-1")) != 0 {
+		t.Error("APP-1 already has an estimate but was updated")
+	}
+	if len(s.received("GET /api/issues/APP-4")) != 0 {
+		t.Error("APP-4 has no estimate to write but was fetched")
+	}
+	if len(s.received("GET /api/issues/APP-5")) != 0 {
+		t.Error("APP-5 is no longer open but was fetched")
+	}
+	posts := s.received("POST /api/issues/APP-2")
+	if len(posts) != 1 {
+		t.Fatalf("got %d updates of APP-2, want 1", len(posts))
+	}
+	update := struct {
+		CustomFields []struct {
+			Name  string `json:"name"`
+			Type  string `json:"$type"`
+			Value struct {
+				Minutes int64 `json:"minutes"`
+			} `json:"value"`
+		} `json:"customFields"`
+	}{}
+	if err := json.Unmarshal([]byte(posts[0].body), &update); err != nil {
+		t.Fatalf("update body %s: %v", posts[0].body, err)
+	}
+	if len(update.CustomFields) != 1 {
+		t.Fatalf("update = %s", posts[0].body)
+	}
+	field := update.CustomFields[0]
+	if field.Name != "Estimation" || field.Type != "PeriodIssueCustomField" || field.Value.Minutes != 150 {
+		t.Errorf("estimation update = %+v, want 150 minutes in Estimation", field)
+	}
+
+	// nguồn của estimate được ghi lại thành comment trên issue
+	comments := s.received("POST /api/issues/APP-2/comments")
+	if len(comments) != 1 || !strings.Contains(comments[0].body, "rule review") {
+		t.Errorf("comments on APP-2 = %+v, want one naming the rule", comments)
+	}
+}
+
+func TestYouTrackRaiseEstimate(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/issues/APP-1", http.StatusOK, `{"idReadable": "APP-1", "customFields": [{"name": "Estimation", "value": {"minutes": 240}}]}`)
+	s.handle("POST /api/issues/APP-1", http.StatusOK, `{"idReadable": "APP-1"}`)
+
+	y := s.youTrack(types.YouTrackConfig{})
+	if err := y.raiseEstimate("APP-1", 3600); err != nil {
+		t.Fatalf("raiseEstimate: %v", err)
+	}
+	if body := s.received("POST /api/issues/APP-1")[0].body; !strings.Contains(body, `"minutes":300`) {
+		t.Errorf("update body %s, want 300 minutes", body)
+	}
+
+	if err := y.raiseEstimate("APP-9", 3600); err == nil {
+		t.Error("raiseEstimate succeeded on an unknown issue")
+	}
+}
+
+func TestYouTrackGetTicketToEstTriesRulesFirst(t *testing.T) {
+	s := newYouTrackStandIn(t)
+	s.handle("GET /api/issues", http.StatusOK, `[{"idReadable": "APP-5", "summary": "[Review] Login page", "customFields": []}]`)
+	y := s.youTrack(types.YouTrackConfig{})
+	y.estimation.Rules = []types.EstimateRule{{Name: "review", Summary: `^\[Review\]`, Estimate: "1h"}}
+
+	tickets, err := y.GetTicketToEst()
+	if err != nil {
+		t.Fatalf("GetTicketToEst: %v", err)
+	}
+	if tickets[0].Est != 3600 || tickets[0].EstimateSource != "rule review" {
+		t.Errorf("APP-5 = %s from %q, want 1h from rule review", helper.FormatEstimate(tickets[0].Est), tickets[0].EstimateSource)
+	}
+	// rule khớp thì không cần tìm issue tương tự
+	if n := len(s.received("GET /api/issues")); n != 1 {
+		t.Errorf("got %d issue searches, want only the user's issues", n)
+	}
+}
+
+func TestYouTrackGetTicketToEstReviewsSuggestions(t *testing.T) {
+	issues := `[
+		{"idReadable": "APP-2", "summary": "Logout button", "customFields": [{"name": "Estimation", "value": null}]},
+		{"idReadable": "APP-3", "summary": "Logout button", "customFields": [{"name": "Estimation", "value": {"minutes": 120}}]}
+	]`
+
+	for _, tc := range []struct {
+		autoAccept float64
+		want       int64
+	}{
+		{autoAccept: 0, want: 2 * 3600},
+		// điểm 1.0 vẫn dưới ngưỡng AutoAcceptScore nên --yes bỏ qua
+		{autoAccept: 1.5, want: 0},
+	} {
+		s := newYouTrackStandIn(t)
+		s.handle("GET /api/issues", http.StatusOK, issues)
+		y := s.youTrack(types.YouTrackConfig{})
+		y.yes = true
+		y.estimation.AutoAcceptScore = tc.autoAccept
+
+		tickets, err := y.GetTicketToEst()
+		if err != nil {
+			t.Fatalf("GetTicketToEst: %v", err)
+		}
+		if tickets[0].Est != tc.want {
+			t.Errorf("AutoAcceptScore %.1f: APP-2 estimate = %d, want %d", tc.autoAccept, tickets[0].Est, tc.want)
+		}
+		if tc.want > 0 && tickets[0].EstimateSource != "similar to APP-3" {
+			t.Errorf("APP-2 estimate source = %q", tickets[0].EstimateSource)
+		}
+	}
+}
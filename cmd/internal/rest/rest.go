@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client is a minimal JSON client for services without a Go SDK.
+type Client struct {
+	BaseURL string
+	header  http.Header
+	client  *http.Client
+}
+
+func NewClient(baseURL string, header http.Header) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		header:  header,
+		client:  http.DefaultClient,
+	}
+}
+
+func BasicAuthHeader(userName string, apiToken string) http.Header {
+	token := base64.StdEncoding.EncodeToString([]byte(userName + ":" + apiToken))
+	return http.Header{"Authorization": []string{"Basic " + token}}
+}
+
+// Do sends body as JSON (or as contentType when set) and decodes the response into out.
+func (c *Client) Do(method string, path string, contentType string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
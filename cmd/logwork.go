@@ -4,14 +4,60 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/configure"
+	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/importer"
 	"github.com/du0ngtrunghieu/luoi-logwork/cmd/internal/logwork"
 	"github.com/du0ngtrunghieu/luoi-logwork/pkg/types"
 	"github.com/spf13/cobra"
 )
 
+// fromTracker imports the real time entries from Toggl Track / Clockify before allocating
+var fromTracker bool
+
+// fromGit lists the local repositories whose commits are used as worklog evidence
+var fromGit []string
+
+// adjustEstimate overrides Worklog.AdjustEstimate of the config for every ticket of this run
+var adjustEstimate string
+
+// dryRun prints the plan without writing anything
+var dryRun bool
+
+// refresh forces a full download of the local issue / worklog cache
+var refresh bool
+
+// offline plans from the local cache only, nothing is fetched or written
+var offline bool
+
+// estimateMode overrides Estimation.Mode of the config: estimate or spent
+var estimateMode string
+
+// estimator overrides Estimation.Estimator of the config: index, v1 or v2
+var estimator string
+
+// assumeYes accepts the estimate suggestions scoring Estimation.AutoAcceptScore without review
+var assumeYes bool
+
+// evalProject and evalSince select the resolved issues est eval replays
+var (
+	evalProject string
+	evalSince   string
+)
+
+// fromDate and toDate select the days of a logwork run, the current week by default
+var (
+	fromDate string
+	toDate   string
+)
+
+// fromICS lists the calendar exports whose meetings are logged to meeting tickets
+var fromICS []string
+
 // logworkCmd represents the logwork command
 var logworkCmd = &cobra.Command{
 	Use:   "logwork",
@@ -31,17 +77,54 @@ var estimateCmd = &cobra.Command{
 	},
 }
 
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Backtest the estimator on resolved issues",
+	Long: `Replays the estimator against resolved issues, each one predicted from the issues resolved
+before it was created, and reports the errors against the time actually spent: MAE, MAPE, the
+coverage of each match threshold and a calibration table. The issues of --project are fetched
+from Jira, without --project the estimation history is replayed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		executeEvaluate()
+	},
+}
+
+func newProjectTracking(config *types.Config) (logwork.ProjectTracking, error) {
+	switch config.EndpointType {
+	case "jira":
+		return logwork.NewJira(config), nil
+	case "azure":
+		return logwork.NewAzureDevOps(config), nil
+	case "youtrack":
+		return logwork.NewYouTrack(config), nil
+	case "linear":
+		return logwork.NewLinear(config), nil
+	default:
+		return nil, errors.New("Endpoint type not supported")
+	}
+}
+
 func execute() {
 	config := &types.Config{}
 	configure.ReadConfig(config)
 
-	var projectTracking logwork.ProjectTracking
+	config.DryRun = dryRun
+	if err := setPeriodFlags(config); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := setCacheFlags(config); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if adjustEstimate != "" {
+		config.Worklog.AdjustEstimate = map[string]string{"*": adjustEstimate}
+	}
 
-	switch config.EndpointType {
-	case "jira":
-		projectTracking = logwork.NewJira(config.Endpoint, config.Username, config.ApiToken)
-	default:
-		fmt.Println("Endpoint type not supported")
+	projectTracking, err := newProjectTracking(config)
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
 
 	tickets, err := projectTracking.GetTicketToLog()
@@ -56,6 +139,42 @@ func execute() {
 		return
 	}
 
+	if fromTracker {
+		if err := importTimeEntries(config.TimeTracker, tickets, dayToLog); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	if len(fromGit) > 0 {
+		if err := importGitActivity(config.Git, fromGit, tickets, dayToLog); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	if len(fromICS) > 0 {
+		if err := importMeetings(config.Calendar, fromICS, tickets, dayToLog); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	if len(config.Recurring) > 0 {
+		recurring, err := importer.NewRecurringWorklogs(config.Recurring)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println("----------------Recurring worklogs-------------------")
+		recurring.Apply(tickets, dayToLog)
+	}
+
+	if err := logwork.ApplyHints(tickets, dayToLog, config.Allocation); err != nil {
+		fmt.Println(err)
+		return
+	}
+
 	err = projectTracking.LogWork(tickets, dayToLog)
 	if err != nil {
 		fmt.Println(err)
@@ -63,16 +182,127 @@ func execute() {
 	}
 }
 
+func importTimeEntries(config types.TimeTrackerConfig, tickets []types.Ticket, dayToLog []types.LogWorkStatus) error {
+	source, err := importer.NewTimeEntrySource(config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("----------------Time entries from %s-------------------\n", config.Type)
+	from, to := importer.DayRange(dayToLog)
+	entries, err := source.GetTimeEntries(from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fetched %d time entries\n", len(entries))
+
+	return importer.ApplyTimeEntries(entries, tickets, dayToLog, config.KeyPattern, config.Type)
+}
+
+func importGitActivity(config types.GitConfig, repos []string, tickets []types.Ticket, dayToLog []types.LogWorkStatus) error {
+	activity, err := importer.NewGitActivity(config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("----------------Git activity-------------------")
+	from, to := importer.DayRange(dayToLog)
+	commits := []importer.Commit{}
+	for _, repo := range repos {
+		repoCommits, err := activity.GetCommits(repo, from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Found %d commits with a ticket key in %s\n", len(repoCommits), repo)
+		commits = append(commits, repoCommits...)
+	}
+
+	activity.ApplyCommits(commits, tickets, dayToLog)
+	return nil
+}
+
+func importMeetings(config types.CalendarConfig, files []string, tickets []types.Ticket, dayToLog []types.LogWorkStatus) error {
+	meetings, err := importer.NewMeetings(config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("----------------Meetings-------------------")
+	from, to := importer.DayRange(dayToLog)
+	events := []importer.Event{}
+	for _, file := range files {
+		fileEvents, err := importer.ReadCalendar(file, from, to)
+		if err != nil {
+			return err
+		}
+		events = append(events, fileEvents...)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+
+	meetings.ApplyEvents(events, tickets, dayToLog)
+	return nil
+}
+
+// setCacheFlags passes --refresh / --offline to the config, only Jira has a local cache.
+func setCacheFlags(config *types.Config) error {
+	if refresh && offline {
+		return errors.New("--refresh and --offline cannot be used together")
+	}
+	if (refresh || offline) && config.EndpointType != "jira" {
+		return fmt.Errorf("--refresh and --offline are only supported for jira, not %s", config.EndpointType)
+	}
+	config.Refresh = refresh
+	config.Offline = offline
+	return nil
+}
+
+// setPeriodFlags passes --from / --to to the config. --to defaults to today.
+func setPeriodFlags(config *types.Config) error {
+	if fromDate == "" {
+		if toDate != "" {
+			return errors.New("--to needs --from")
+		}
+		return nil
+	}
+
+	from, err := time.ParseInLocation(time.DateOnly, fromDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid --from %q, expected YYYY-MM-DD", fromDate)
+	}
+	to := time.Now()
+	if toDate != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, toDate, time.Local); err != nil {
+			return fmt.Errorf("invalid --to %q, expected YYYY-MM-DD", toDate)
+		}
+	}
+	if to.Before(from) {
+		return fmt.Errorf("--to %s is before --from %s", to.Format(time.DateOnly), fromDate)
+	}
+
+	config.From = from
+	config.To = to
+	return nil
+}
+
 func executeEstimate() {
 	config := &types.Config{}
 	configure.ReadConfig(config)
-	var projectTracking logwork.ProjectTracking
+	if err := setCacheFlags(config); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if estimateMode != "" {
+		config.Estimation.Mode = estimateMode
+	}
+	if estimator != "" {
+		config.Estimation.Estimator = estimator
+	}
+	config.Yes = assumeYes
 
-	switch config.EndpointType {
-	case "jira":
-		projectTracking = logwork.NewJira(config.Endpoint, config.Username, config.ApiToken)
-	default:
-		fmt.Println("Endpoint type not supported")
+	projectTracking, err := newProjectTracking(config)
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
 
 	tickets, err := projectTracking.GetTicketToEst()
@@ -85,9 +315,65 @@ func executeEstimate() {
 	}
 
 }
+func executeEvaluate() {
+	config := &types.Config{}
+	configure.ReadConfig(config)
+	if err := setCacheFlags(config); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if estimateMode != "" {
+		config.Estimation.Mode = estimateMode
+	}
+	if estimator != "" {
+		config.Estimation.Estimator = estimator
+	}
+
+	since := time.Now().AddDate(0, -3, 0)
+	if evalSince != "" {
+		var err error
+		if since, err = time.ParseInLocation("2006-01-02", evalSince, time.Local); err != nil {
+			fmt.Printf("Invalid --since %q, expected YYYY-MM-DD\n", evalSince)
+			return
+		}
+	}
+
+	projectTracking, err := newProjectTracking(config)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	evaluator, ok := projectTracking.(logwork.EstimatorEvaluator)
+	if !ok {
+		fmt.Printf("est eval is not supported for %s\n", config.EndpointType)
+		return
+	}
+	if err := evaluator.EvaluateEstimator(evalProject, since); err != nil {
+		fmt.Println("Error evaluating the estimator:", err)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(logworkCmd)
 	rootCmd.AddCommand(estimateCmd)
+	estimateCmd.AddCommand(evalCmd)
+
+	logworkCmd.Flags().StringSliceVar(&fromGit, "from-git", nil, "comma separated local git repositories whose commits are used as worklog evidence")
+	logworkCmd.Flags().StringSliceVar(&fromICS, "from-ics", nil, "comma separated .ics calendar exports whose meetings are logged to meeting tickets")
+	logworkCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the worklogs, estimate changes and transitions that would be made, without writing anything")
+	logworkCmd.Flags().StringVar(&adjustEstimate, "adjust-estimate", "", "how worklogs move the remaining estimate for this run: auto, leave, new:<duration> or manual:<duration>")
+	logworkCmd.Flags().BoolVar(&refresh, "refresh", false, "download the local issue and worklog cache again instead of syncing the changes")
+	logworkCmd.Flags().BoolVar(&offline, "offline", false, "plan from the local cache without any network access, nothing is logged")
+	estimateCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "download the local issue and worklog cache again instead of syncing the changes")
+	estimateCmd.PersistentFlags().BoolVar(&offline, "offline", false, "suggest estimates from the local cache without any network access, nothing is updated")
+	estimateCmd.PersistentFlags().StringVar(&estimateMode, "mode", "", "estimate copies the original estimate of the best match, spent predicts from the time spent on similar issues")
+	estimateCmd.PersistentFlags().StringVar(&estimator, "estimator", "", "how similar issues are found: index (local history, default), v1 (Jira summary search) or v2 (Jira search with context)")
+	estimateCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "accept the suggestions scoring Estimation.AutoAcceptScore (default 0.9) or more without review, skip the others")
+	evalCmd.Flags().StringVar(&evalProject, "project", "", "project key of the resolved issues to replay, fetched from Jira; every project of the estimation history by default")
+	evalCmd.Flags().StringVar(&evalSince, "since", "", "replay the issues resolved since this date (YYYY-MM-DD), 3 months ago by default")
+	logworkCmd.Flags().BoolVar(&fromTracker, "from-tracker", false, "use time entries from Toggl Track / Clockify (see TimeTracker in config) as the source of truth")
+	logworkCmd.Flags().StringVar(&fromDate, "from", "", "first day to log (YYYY-MM-DD), the current week by default")
+	logworkCmd.Flags().StringVar(&toDate, "to", "", "last day to log (YYYY-MM-DD), today by default when --from is set")
 
 	// Here you will define your flags and configuration settings.
 
@@ -5,6 +5,8 @@ go 1.22.3
 require (
 	github.com/andygrunwald/go-jira v1.17.0
 	github.com/spf13/cobra v1.8.0
+	go.etcd.io/bbolt v1.3.11
+	golang.org/x/text v0.21.0
 )
 
 require (
@@ -15,4 +17,5 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/trivago/tgo v1.0.7 // indirect
+	golang.org/x/sys v0.26.0 // indirect
 )
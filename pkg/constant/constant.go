@@ -1,3 +1,12 @@
 package constant
 
 const ConfigFile = ".luoi-logwork.conf"
+
+// LedgerFile keeps worklogs for trackers that have no worklog entity of their own
+const LedgerFile = ".luoi-logwork-ledger.json"
+
+// DefaultShiftSeconds is the working time of a day (7.5h)
+const DefaultShiftSeconds = int64(7.5 * 3600)
+
+// CacheDir is the directory of the local issue / worklog cache under the user cache dir
+const CacheDir = "luoi-logwork"
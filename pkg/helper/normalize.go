@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Text normalization shared by keyword extraction, Tokenize and StringSimilarity, so that
+// "Sửa lỗi đăng nhập" and "Sua loi dang nhap" compare equal:
+//  1. lowercase and compose (NFC), some IMEs and macOS type "ử" as "u" + two combining marks
+//  2. strip Jira keys (ABC-123) and plain numbers
+//  3. fold the diacritics (ạ, ắ, đ ... => a, a, d)
+//  4. drop the vi / en stopwords
+//  5. stem the English plurals and -ing / -ed forms
+
+var (
+	// combining marks belong to their word even when they do not compose
+	nonWordRe = regexp.MustCompile(`[^\p{L}\p{M}\p{N}]+`)
+	jiraKeyRe = regexp.MustCompile(`\b\p{L}[\p{L}\p{N}_]*-\d+\b`)
+	numberRe  = regexp.MustCompile(`^\d+$`)
+)
+
+// stopwords are compared after folding, so the Vietnamese ones are written without diacritics.
+// "fix" / "sua" are in every other summary and say nothing about the size of the work.
+var stopwords = toSet(
+	// tiếng Việt
+	// không có "dang" (đăng), "tai" (tải), "ve" (vẽ), "tu" (tự): sau khi bỏ dấu chúng trùng với từ có nghĩa
+	"cua", "cho", "va", "cac", "nhung", "la", "voi", "trong", "tren", "duoi", "duoc", "khi",
+	"thi", "mot", "de", "den", "bi", "da", "se", "lai", "nay", "do", "theo", "khong", "co",
+	"sua", "can", "phai", "nhu", "hay", "hoac", "neu", "vao", "ra",
+	// English
+	"the", "an", "of", "for", "to", "in", "on", "at", "and", "or", "with", "by", "from", "is",
+	"are", "be", "as", "this", "that", "it", "its", "into", "when", "not", "no", "fix", "fixed",
+	"should", "can", "all", "some", "via",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
+
+// FoldDiacritics lowercases s and removes the diacritics: decomposed (NFD), the combining
+// marks dropped, then đ => d which is a letter of its own and not a d with a mark.
+func FoldDiacritics(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		if r == 'đ' {
+			return 'd'
+		}
+		return r
+	}, norm.NFD.String(strings.ToLower(s)))
+}
+
+// normalizedWord is one word of a text: as written (lowercased) and normalized.
+type normalizedWord struct {
+	surface string
+	norm    string
+}
+
+// normalizeWords runs the normalization pipeline, keeping the words in order.
+func normalizeWords(s string) []normalizedWord {
+	s = jiraKeyRe.ReplaceAllString(norm.NFC.String(strings.ToLower(s)), " ")
+
+	words := []normalizedWord{}
+	for _, surface := range nonWordRe.Split(s, -1) {
+		if surface == "" || numberRe.MatchString(surface) {
+			continue
+		}
+		folded := FoldDiacritics(surface)
+		if stopwords[folded] {
+			continue
+		}
+		words = append(words, normalizedWord{surface: surface, norm: stem(folded)})
+	}
+	return words
+}
+
+// NormalizeText returns the normalized words of s joined by a space.
+func NormalizeText(s string) string {
+	norms := []string{}
+	for _, w := range normalizeWords(s) {
+		norms = append(norms, w.norm)
+	}
+	return strings.Join(norms, " ")
+}
+
+// stem strips the common English suffixes. Folded Vietnamese syllables never end with them.
+func stem(w string) string {
+	n := len(w)
+	switch {
+	case n > 4 && strings.HasSuffix(w, "ies"):
+		return w[:n-3] + "y"
+	case n > 5 && strings.HasSuffix(w, "ing"):
+		return undouble(w[:n-3])
+	case n > 4 && strings.HasSuffix(w, "ed"):
+		return undouble(w[:n-2])
+	case n > 4 && (strings.HasSuffix(w, "sses") || strings.HasSuffix(w, "xes") ||
+		strings.HasSuffix(w, "ches") || strings.HasSuffix(w, "shes")):
+		return w[:n-2]
+	case n > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") &&
+		!strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		return w[:n-1]
+	}
+	return w
+}
+
+// undouble trims the doubled consonant left by the suffix: running => runn => run
+func undouble(w string) string {
+	n := len(w)
+	if n > 2 && w[n-1] == w[n-2] && !strings.ContainsRune("aeiouls", rune(w[n-1])) {
+		return w[:n-1]
+	}
+	return w
+}
@@ -0,0 +1,41 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestNormalizeTextFoldsDiacritics(t *testing.T) {
+	nfc := "Sửa lỗi đăng nhập"
+	nfd := norm.NFD.String(nfc)
+	if nfd == nfc {
+		t.Fatal("test input is not decomposed")
+	}
+
+	for _, s := range []string{nfc, nfd, "Sua loi dang nhap", "SỬA LỖI ĐĂNG NHẬP"} {
+		if got := NormalizeText(s); got != "loi dang nhap" {
+			t.Errorf("NormalizeText(%q) = %q, want %q", s, got, "loi dang nhap")
+		}
+	}
+	if got := StringSimilarity(nfd, nfc); got != 1 {
+		t.Errorf("StringSimilarity(NFD, NFC) = %.2f, want 1", got)
+	}
+}
+
+func TestExtractKeywordsKeepsComposedSurface(t *testing.T) {
+	got := ExtractKeywords(norm.NFD.String("[ABC-123] Sửa lỗi của trang đăng nhập cho 2 users"), 3)
+	want := "lỗi,loi,trang,đăng,dang,nhập,nhap,users"
+	if strings.Join(got, ",") != want {
+		t.Errorf("ExtractKeywords = %v, want %s", got, want)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Fix the failing tests of APP-42 in 3 stories")
+	want := "fail,test,story"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Tokenize = %v, want %s", got, want)
+	}
+}
@@ -2,12 +2,17 @@ package helper
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 )
 
+// StringSimilarity là Jaro-Winkler của hai text đã chuẩn hóa (xem normalize.go), trong [0, 1]
 func StringSimilarity(s1, s2 string) float64 {
-	s1, s2 = strings.ToLower(strings.TrimSpace(s1)), strings.ToLower(strings.TrimSpace(s2))
+	n1, n2 := NormalizeText(s1), NormalizeText(s2)
+	if n1 == "" && n2 == "" {
+		// toàn stopword: so sánh bản bỏ dấu
+		n1, n2 = FoldDiacritics(strings.TrimSpace(s1)), FoldDiacritics(strings.TrimSpace(s2))
+	}
+	s1, s2 = n1, n2
 	if s1 == s2 {
 		return 1.0
 	}
@@ -115,34 +120,52 @@ func FormatEstimate(seconds int64) string {
 		result += fmt.Sprintf("%dh ", hours)
 	}
 	if minutes > 0 {
-		result += fmt.Sprintf("%dm", minutes)
+		result += fmt.Sprintf("%dm ", minutes)
+	}
+	// giây lẻ (ví dụ từ Toggl) vẫn hiện ra để không bị làm tròn ngầm
+	if rest := seconds % 60; rest > 0 {
+		result += fmt.Sprintf("%ds", rest)
 	}
 
-	return result
+	return strings.TrimSpace(result)
 }
 
-// extractKeywords: tách các từ dài >= minLen, loại bỏ ký tự không phải chữ/ số
+// extractKeywords: các từ đã chuẩn hóa dài >= minLen ký tự (bỏ key Jira, số, stopword vi/en),
+// tối đa 6 từ. Từ có dấu được trả về cả dạng viết và dạng bỏ dấu ("lỗi", "loi") để search
+// khớp cả summary gõ không dấu.
 func ExtractKeywords(s string, minLen int) []string {
-	re := regexp.MustCompile(`[^\p{L}\p{N}]+`) // split on non letter/number (unicode-aware)
-	parts := re.Split(strings.ToLower(strings.TrimSpace(s)), -1)
-
 	seen := map[string]bool{}
 	keywords := []string{}
-	for _, p := range parts {
-		if len(p) >= minLen {
-			if !seen[p] {
-				seen[p] = true
-				keywords = append(keywords, p)
-			}
+	words := 0
+	for _, w := range normalizeWords(strings.TrimSpace(s)) {
+		if len([]rune(w.norm)) < minLen || seen[w.norm] {
+			continue
+		}
+		seen[w.norm] = true
+		keywords = append(keywords, w.surface)
+		if folded := FoldDiacritics(w.surface); folded != w.surface {
+			keywords = append(keywords, folded)
 		}
 		// stop early if too many keywords
-		if len(keywords) >= 6 {
+		if words++; words >= 6 {
 			break
 		}
 	}
 	return keywords
 }
 
+// Tokenize tách text thành các từ đã chuẩn hóa (xem normalize.go), bỏ các từ 1 ký tự, giữ từ
+// lặp để tính tần suất
+func Tokenize(s string) []string {
+	tokens := []string{}
+	for _, w := range normalizeWords(s) {
+		if len([]rune(w.norm)) >= 2 {
+			tokens = append(tokens, w.norm)
+		}
+	}
+	return tokens
+}
+
 // buildJQLForKeywords: tạo JQL phần tìm summary ~ "kw1" OR summary ~ "kw2" ...
 func BuildJQLForKeywords(keywords []string) string {
 	clauses := []string{}
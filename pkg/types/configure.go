@@ -1,8 +1,213 @@
 package types
 
+import "time"
+
 type Config struct {
 	Username     string
 	ApiToken     string
 	Endpoint     string
 	EndpointType string
+	YouTrack     YouTrackConfig
+	Linear       LinearConfig
+	Tempo        TempoConfig
+	TimeTracker  TimeTrackerConfig
+	Git          GitConfig
+	Calendar     CalendarConfig
+	Recurring    []RecurringRule
+	Allocation   AllocationConfig
+	Worklog      WorklogConfig
+	Transitions  []TransitionRule
+	Estimation   EstimationConfig
+	// DryRun is set by --dry-run: the plan is printed and nothing is written
+	DryRun bool `json:"-"`
+	// From and To are set by --from / --to: the first and last day of the run, the current
+	// week when From is zero
+	From time.Time `json:"-"`
+	To   time.Time `json:"-"`
+	// Refresh is set by --refresh: the local cache is downloaded again from scratch
+	Refresh bool `json:"-"`
+	// Offline is set by --offline: everything is read from the local cache, nothing is written
+	Offline bool `json:"-"`
+	// Yes is set by est --yes: estimate suggestions are accepted without review
+	Yes bool `json:"-"`
+}
+
+// YouTrackConfig holds the YouTrack specific settings, empty values fall back to the defaults
+type YouTrackConfig struct {
+	Query           string
+	EstimationField string
+	SpentTimeField  string
+	WorkType        string
+}
+
+// LinearConfig maps Linear story points to hours, e.g. {"1": 1, "2": 2, "3": 4, "5": 8}
+type LinearConfig struct {
+	PointsToHours map[string]float64
+}
+
+// TempoConfig routes Jira worklogs through Tempo Timesheets when ApiToken is set.
+// Accounts, Attributes and NonBillable are keyed by ticket key, project key or "*".
+type TempoConfig struct {
+	ApiToken       string
+	Endpoint       string
+	Accounts       map[string]string
+	Attributes     map[string]map[string]string
+	NonBillable    []string
+	RequireAccount bool
+}
+
+// TimeTrackerConfig is the Toggl Track / Clockify account used by logwork --from-tracker.
+// KeyPattern is the regex matching ticket keys in entry descriptions and tags.
+type TimeTrackerConfig struct {
+	Type        string
+	ApiToken    string
+	WorkspaceID string
+	KeyPattern  string
+}
+
+// GitConfig drives logwork --from-git. Author defaults to the repo's user.email, SessionGap
+// ("2h") splits commits into work sessions, SessionPadding ("30m") is the work counted
+// before the first commit of a session. Mode is "weights" (default) or "fixed".
+type GitConfig struct {
+	Author         string
+	SessionGap     string
+	SessionPadding string
+	Mode           string
+	KeyPattern     string
+}
+
+// CalendarConfig picks the ticket meetings imported by logwork --from-ics are logged to.
+// The first rule matching wins, meetings matching no rule go to DefaultTicket, or are only
+// subtracted from the shift when DefaultTicket is empty.
+type CalendarConfig struct {
+	Rules         []MeetingRule
+	DefaultTicket string
+}
+
+// MeetingRule matches a meeting by regex on its title and/or organizer (name or email)
+type MeetingRule struct {
+	Title     string
+	Organizer string
+	Ticket    string
+}
+
+// RecurringRule logs a fixed worklog before allocation, e.g. 15m "daily" on ABC-1 at "09:00"
+// or 1h every "friday" on ABC-2. Every is "daily" (working days) or comma separated weekdays.
+type RecurringRule struct {
+	Ticket   string
+	Summary  string
+	Duration string
+	Every    string
+	At       string
+}
+
+// TransitionRule moves a Jira issue through its workflow during a logwork run. On is the event:
+// "first-worklog" (first worklog of the issue in the run), "worklog" (every worklog),
+// "estimate-reached" (time spent reaches the original estimate) or "end-of-run". FromCategory
+// (status category key or name, e.g. "new" or "In Progress") and FromStatus restrict the current
+// status. Transition is the transition name or ID and Fields fills its screen, e.g.
+// {"resolution": {"name": "Done"}}. Without Transitions in the config, Open issues are paused
+// after a worklog; an empty list disables transitions.
+type TransitionRule struct {
+	On           string
+	FromCategory string
+	FromStatus   string
+	Transition   string
+	Fields       map[string]interface{}
+}
+
+// WorklogConfig sets how worklogs are written. AdjustEstimate maps a ticket key, project key
+// or "*" to how the remaining estimate moves: "auto" (default), "leave", "new:2h" (set it to
+// 2h) or "manual:30m" (reduce it by 30m). Only Jira (Tempo included) and Azure DevOps keep a
+// remaining estimate.
+//
+// Comment maps a ticket key, project key or "*" to a text/template for the worklog comment,
+// with the fields TicketID, Summary, Project, Date, Duration, Seconds, Source, RunID and
+// Evidence (commit subjects, meeting titles...), e.g. "{{.Summary}} ({{.RunID}})".
+type WorklogConfig struct {
+	AdjustEstimate map[string]string
+	Comment        map[string]string
+}
+
+// EstimationConfig drives the estimate suggestions of `est`, drawn from a local index of
+// resolved issues. HistoryJQL narrows the issues indexed (default: the projects of the user's
+// issues), HistoryDays is how far back the index goes (default 365) and HistoryRefresh how
+// old it may get before the new resolutions are synced (default "24h").
+//
+// Mode is "estimate" (default) to copy the original estimate of the best match, or "spent" to
+// predict from the time spent on the TopK (default 5) most similar issues: weighted median,
+// outliers trimmed, shown with an 80% interval (weighted 10th and 90th quantiles).
+// PersonalRatio scales that prediction by how the user's time spent compares to their
+// estimates, relative to the other assignees.
+//
+// Estimator picks how similar issues are found: "index" (default) ranks the local history
+// with BM25, "v1" searches Jira by summary keywords and scores the summary only, "v2" searches
+// the project / parent of the ticket. index and v2 score TextWeight * text + ContextWeight *
+// context (default 0.6 / 0.4). ContextWeights weighs the context factors "project" (0.4),
+// "parent" (0.3), "labels" (0.2) and "recency" (0.1), recency fading over DecayDays (180)
+// between the creation dates. MatchThreshold is the score the best match needs (default 0.7
+// for index, 0.95 for v1, 0.9 for v2).
+//
+// Suggestions are reviewed one by one before anything is written. With est --yes, the ones
+// scoring AutoAcceptScore (default 0.9) or more are accepted and the others skipped. Rules
+// fill matching tickets first, without review.
+type EstimationConfig struct {
+	Estimator       string
+	TextWeight      float64
+	ContextWeight   float64
+	ContextWeights  map[string]float64
+	DecayDays       int
+	MatchThreshold  float64
+	HistoryJQL      string
+	HistoryDays     int
+	HistoryRefresh  string
+	Mode            string
+	TopK            int
+	PersonalRatio   bool
+	AutoAcceptScore float64
+	Rules           []EstimateRule
+}
+
+// EstimateRule gives a fixed estimate to the tickets it matches, e.g. {"Name": "review",
+// "Summary": "^\\[Review\\]", "Estimate": "1h"}. Rules are tried in order before the similarity
+// search and the first match wins. Summary is a regex, Type, Labels and Components are globs
+// ("Sub-task", "unit-*"), case-insensitive; each label / component glob must match one of the
+// ticket's. Empty fields match anything. Estimate is a duration: "1h", "2h30m". Labels are the
+// tags on Azure DevOps and YouTrack; only Jira has components, elsewhere a rule with
+// Components never matches.
+type EstimateRule struct {
+	Name       string
+	Summary    string
+	Type       string
+	Labels     []string
+	Components []string
+	Estimate   string
+}
+
+// AllocationConfig tunes the allocation algorithm. Hints maps a ticket key to its hints,
+// e.g. "max-per-day: 4h; weight: 2; pin: on 2026-10-20 3h" or "exclude". HintField is the
+// Jira custom field (e.g. customfield_10100) holding the same hints text.
+//
+// Rounding (e.g. "15m", "30m") rounds the allocated worklogs of each day, MinChunk (e.g. "30m")
+// merges smaller worklogs into the largest one of the day. Remainder says who takes the part
+// of the day that is not a multiple of Rounding: "largest" (default) or "last" worklog, or
+// "drop" to leave it unlogged. Fixed worklogs (imported, pinned, recurring) are never rounded.
+//
+// DayStart (HH:MM, default 07:30) is when the first worklog of a day starts, the next ones
+// follow it, skipping Lunch (e.g. "12:00-13:00"), busy periods and existing worklogs.
+//
+// Overflow decides what fills a day once every ticket is at its estimate: "gap" (default)
+// leaves it unlogged and reports it, "recent" logs it on the most recently updated tickets,
+// "fallback" logs it on FallbackTicket and "raise" does like "recent" and raises the estimates.
+type AllocationConfig struct {
+	Hints     map[string]string
+	HintField string
+	Rounding  string
+	MinChunk  string
+	Remainder string
+	DayStart  string
+	Lunch     string
+
+	Overflow       string
+	FallbackTicket string
 }
@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+// AllocationHints tune how the allocation algorithm treats a ticket
+type AllocationHints struct {
+	// Weight is the share of the day compared to the other tickets (default 1)
+	Weight float64
+	// MaxPerDay caps the time logged on the ticket per day, 0 means no cap
+	MaxPerDay int64
+	// Exclude keeps the algorithm from allocating anything to the ticket
+	Exclude bool
+	// Pins are fixed worklogs on a given date
+	Pins []Pin
+}
+
+type Pin struct {
+	Date      time.Time
+	TimeToLog int64
+}
+
+// Merge overrides h with the hints set in other, pins are added up
+func (h AllocationHints) Merge(other AllocationHints) AllocationHints {
+	if other.Weight > 0 {
+		h.Weight = other.Weight
+	}
+	if other.MaxPerDay > 0 {
+		h.MaxPerDay = other.MaxPerDay
+	}
+	if other.Exclude {
+		h.Exclude = true
+	}
+	h.Pins = append(h.Pins, other.Pins...)
+	return h
+}
@@ -0,0 +1,9 @@
+package types
+
+import "time"
+
+// Interval is a period of time inside a day
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
@@ -3,7 +3,11 @@ package types
 import "time"
 
 type LogAction struct {
-	TimeToLog     int64
-	DateToLog     time.Time
-	TicketToLog   Ticket
+	TimeToLog   int64
+	DateToLog   time.Time
+	TicketToLog Ticket
+	// Source tells where the worklog comes from (toggl, clockify...), empty for the allocation algorithm
+	Source string
+	// Evidence backs the worklog up (commit subjects, meeting titles...) in its comment
+	Evidence []string
 }
@@ -9,6 +9,20 @@ import (
 type LogWorkStatus struct {
 	Date      time.Time
 	TimeSpent int64
+	// Fixed are worklogs decided before the allocation algorithm runs (e.g. imported time entries)
+	Fixed []LogAction
+	// Busy are periods of the day not available for allocation and not logged (e.g. meetings without ticket)
+	Busy []Interval
+	// Logged are the periods of the existing worklogs counted in TimeSpent, when their start is known
+	Logged []Interval
+	// Tickets maps ticket ID to the time of its existing worklogs this day, when the backend tells
+	Tickets map[string]int64
+	// Weights maps ticket ID to an allocation weight for this day (e.g. from git activity)
+	Weights map[string]float64
+	// Evidence maps ticket ID to what backs up the time allocated to it this day (e.g. commit subjects)
+	Evidence map[string][]string
+	// Allocatable caps the time the algorithm may spread on this day, nil means the rest of the shift
+	Allocatable *int64
 }
 
 func (l *LogWorkStatus) New(date time.Time, timeSpent int64) (*LogWorkStatus, error) {
@@ -28,3 +42,55 @@ func (l *LogWorkStatus) Add(timeSpent int64) error {
 
 	return nil
 }
+
+// AddWorklog counts an existing worklog started at started, keeping its period so new
+// worklogs are not scheduled over it.
+func (l *LogWorkStatus) AddWorklog(started time.Time, timeSpent int64) error {
+	l.Logged = append(l.Logged, Interval{Start: started, End: started.Add(time.Duration(timeSpent) * time.Second)})
+	return l.Add(timeSpent)
+}
+
+// AddTicketTime records that timeSpent of the day is already logged on ticketID. TimeSpent
+// is counted by Add / AddWorklog.
+func (l *LogWorkStatus) AddTicketTime(ticketID string, timeSpent int64) {
+	if l.Tickets == nil {
+		l.Tickets = map[string]int64{}
+	}
+	l.Tickets[ticketID] += timeSpent
+}
+
+func (l *LogWorkStatus) AddFixed(action LogAction) {
+	l.Fixed = append(l.Fixed, action)
+}
+
+// FixedTime returns the total time of the fixed worklogs of the day
+func (l *LogWorkStatus) FixedTime() int64 {
+	total := int64(0)
+	for _, f := range l.Fixed {
+		total += f.TimeToLog
+	}
+	return total
+}
+
+func (l *LogWorkStatus) AddWeight(ticketID string, weight float64) {
+	if l.Weights == nil {
+		l.Weights = map[string]float64{}
+	}
+	l.Weights[ticketID] += weight
+}
+
+func (l *LogWorkStatus) AddEvidence(ticketID string, evidence ...string) {
+	if l.Evidence == nil {
+		l.Evidence = map[string][]string{}
+	}
+	l.Evidence[ticketID] = append(l.Evidence[ticketID], evidence...)
+}
+
+// BusyTime returns the total time of the busy periods of the day
+func (l *LogWorkStatus) BusyTime() int64 {
+	total := int64(0)
+	for _, b := range l.Busy {
+		total += int64(b.End.Sub(b.Start).Seconds())
+	}
+	return total
+}
@@ -5,12 +5,19 @@ import "github.com/andygrunwald/go-jira"
 type Ticket struct {
 	ID              string
 	Summary         string
+	Description     string
 	Est             int64
 	EstimatedLogged int64
+	Remaining       int64
 	Status          string
 	Type            string
 	Project         string
 	Labels          []string
+	Components      []string
 	Parent          string
 	Created         jira.Time
+	Updated         jira.Time
+	Hints           AllocationHints
+	// EstimateSource says where a filled Est comes from, e.g. "rule review" or "similar to ABC-12"
+	EstimateSource string
 }
@@ -0,0 +1,11 @@
+package types
+
+import "time"
+
+// TimeEntry is a time entry tracked in an external tool such as Toggl Track or Clockify
+type TimeEntry struct {
+	Description string
+	Tags        []string
+	Start       time.Time
+	Duration    int64
+}